@@ -0,0 +1,52 @@
+//go:build go1.18
+// +build go1.18
+
+package bananoutils_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+func FuzzPubKeyToAddress(f *testing.F) {
+	f.Add(bytes.Repeat([]byte{0}, 32))
+	f.Add(bytes.Repeat([]byte{0xff}, 32))
+	f.Fuzz(func(t *testing.T, pub []byte) {
+		if len(pub) != 32 {
+			return
+		}
+		for _, prefix := range []string{bananoutils.PrefixBanano, bananoutils.PrefixNano, bananoutils.PrefixXRB} {
+			account := bananoutils.PubKeyToAddressWithPrefix(pub, prefix)
+			decoded, err := bananoutils.AddressToPub(account)
+			if err != nil {
+				t.Fatalf("%s did not decode: %s", account, err)
+			}
+			if !bytes.Equal(decoded, pub) {
+				t.Fatalf("%s decoded to %x, want %x", account, decoded, pub)
+			}
+		}
+	})
+}
+
+func FuzzAddressToPub(f *testing.F) {
+	f.Add(testAddress)
+	f.Add("nano_" + testAddress[4:])
+	f.Add("ban_")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, account string) {
+		pub, err := bananoutils.AddressToPub(bananoutils.Account(account))
+		if err != nil {
+			return
+		}
+		// Every accepted address has exactly one encoding.
+		prefix, err := bananoutils.AddressPrefix(bananoutils.Account(account))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded := bananoutils.PubKeyToAddressWithPrefix(pub, prefix); string(encoded) != account {
+			t.Fatalf("%q decoded to a key that encodes as %q", account, encoded)
+		}
+	})
+}
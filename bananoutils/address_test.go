@@ -0,0 +1,64 @@
+package bananoutils_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+const testAddress = "ban_3wtsduys8b7jkbfwwfzx3jgpgpsi9b8zurfe9bp1p5cdxkqiz7a5wxcoo7ba"
+
+func TestAddressToPubErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		account string
+		err     error
+	}{
+		{"empty", "", bananoutils.ErrAddressPrefix},
+		{"short", "ba", bananoutils.ErrAddressPrefix},
+		{"prefix only", "nano_", bananoutils.ErrAddressLength},
+		{"unknown prefix", "btc_" + testAddress[4:], bananoutils.ErrAddressPrefix},
+		{"upper case prefix", "BAN_" + testAddress[4:], bananoutils.ErrAddressPrefix},
+		{"too short", testAddress[:len(testAddress)-1], bananoutils.ErrAddressLength},
+		{"too long", testAddress + "1", bananoutils.ErrAddressLength},
+		{"bad character", testAddress[:10] + "0" + testAddress[11:], bananoutils.ErrAddressAlphabet},
+		{"padding character", testAddress[:10] + "=" + testAddress[11:], bananoutils.ErrAddressAlphabet},
+		{"upper case", testAddress[:10] + "B" + testAddress[11:], bananoutils.ErrAddressAlphabet},
+		{"unused bits set", "ban_4" + testAddress[5:], bananoutils.ErrAddressAlphabet},
+		{"bad checksum", testAddress[:len(testAddress)-1] + "1", bananoutils.ErrAddressChecksum},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := bananoutils.AddressToPub(bananoutils.Account(test.account))
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+			var addressErr *bananoutils.AddressError
+			if !errors.As(err, &addressErr) {
+				t.Errorf("expected an AddressError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestAddressPrefixes(t *testing.T) {
+	want, err := bananoutils.AddressToPub(testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{bananoutils.PrefixBanano, bananoutils.PrefixNano, bananoutils.PrefixXRB} {
+		account := bananoutils.PubKeyToAddressWithPrefix(want, prefix)
+		if string(account) != prefix+testAddress[4:] {
+			t.Errorf("expected %s%s, got %s", prefix, testAddress[4:], account)
+		}
+		got, err := bananoutils.AddressToPub(account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s decoded to a different key", account)
+		}
+	}
+}
@@ -2,19 +2,19 @@ package bananoutils
 
 import (
 	"bytes"
-	"crypto/rand"
 	"encoding/base32"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 
 	"golang.org/x/crypto/blake2b"
 
 	// We've forked golang's ed25519 implementation
 	// to use blake2b instead of sha3
 	"github.com/bbedward/crypto/ed25519"
-	log "github.com/sirupsen/logrus"
 )
 
 // nano uses a non-standard base32 character set.
@@ -29,52 +29,102 @@ func Reversed(str []byte) (result []byte) {
 	return result
 }
 
+// ErrNoSecret is returned for accounts only known by their address.
+var ErrNoSecret = errors.New("only the address is known, there is no secret for it")
+
+// Address prefixes accepted when parsing, they all encode the same key.
+const (
+	PrefixBanano = "ban_"
+	PrefixNano   = "nano_"
+	PrefixXRB    = "xrb_"
+)
+
+// addressKeyLength is the length of the encoded key and checksum after the prefix.
+const addressKeyLength = 60
+
+var (
+	ErrAddressPrefix   = errors.New("unknown address prefix")
+	ErrAddressLength   = errors.New("invalid address length")
+	ErrAddressAlphabet = errors.New("invalid address character")
+	ErrAddressChecksum = errors.New("invalid address checksum")
+)
+
+// AddressError is returned for addresses that cannot be parsed, Err is one of
+// the ErrAddress errors so it can be checked with errors.Is.
+type AddressError struct {
+	Account Account
+	Err     error
+}
+
+func (err *AddressError) Error() string {
+	return fmt.Sprintf("%s: %q", err.Err, err.Account)
+}
+
+func (err *AddressError) Unwrap() error {
+	return err.Err
+}
+
 func ValidateAddress(account Account) bool {
 	_, err := AddressToPub(account)
 
 	return err == nil
 }
 
+// AddressPrefix returns the known prefix account starts with.
+func AddressPrefix(account Account) (string, error) {
+	for _, prefix := range []string{PrefixBanano, PrefixNano, PrefixXRB} {
+		if strings.HasPrefix(string(account), prefix) {
+			return prefix, nil
+		}
+	}
+	return "", &AddressError{Account: account, Err: ErrAddressPrefix}
+}
+
 func AddressToPub(account Account) (public_key []byte, err error) {
-	address := string(account)
-
-	if address[:4] == "xrb_" || address[:4] == "ban_" {
-		address = address[4:]
-	} else if address[:5] == "nano_" {
-		address = address[5:]
-	} else {
-		return nil, errors.New("invalid address format")
-	}
-	// A valid nano address is 64 bytes long
-	// First 5 are simply a hard-coded string nano_ for ease of use
-	// The following 52 characters form the address, and the final
+	prefix, err := AddressPrefix(account)
+	if err != nil {
+		return nil, err
+	}
+	address := string(account)[len(prefix):]
+
+	// A valid address is the prefix followed by 60 characters.
+	// The first 52 characters form the public key, and the final
 	// 8 are a checksum.
 	// They are base 32 encoded with a custom encoding.
-	if len(address) == 60 {
-		// The nano address string is 260bits which doesn't fall on a
-		// byte boundary. pad with zeros to 280bits.
-		// (zeros are encoded as 1 in nano's 32bit alphabet)
-		key_b32nano := "1111" + address[0:52]
-		input_checksum := address[52:]
-
-		key_bytes, err := NanoEncoding.DecodeString(key_b32nano)
-		if err != nil {
-			return nil, err
-		}
-		// strip off upper 24 bits (3 bytes). 20 padding was added by us,
-		// 4 is unused as account is 256 bits.
-		key_bytes = key_bytes[3:]
-
-		// nano checksum is calculated by hashing the key and reversing the bytes
-		valid := NanoEncoding.EncodeToString(GetAddressChecksum(key_bytes)) == input_checksum
-		if valid {
-			return key_bytes, nil
-		} else {
-			return nil, errors.New("invalid address checksum")
+	if len(address) != addressKeyLength {
+		return nil, &AddressError{Account: account, Err: ErrAddressLength}
+	}
+	for _, c := range address {
+		if !strings.ContainsRune(EncodeNano, c) {
+			return nil, &AddressError{Account: account, Err: ErrAddressAlphabet}
 		}
 	}
+	// The key is 256 bits in 260 encoded bits, the unused top bits of the
+	// first character must be zero or different addresses would give the
+	// same key.
+	if address[0] != '1' && address[0] != '3' {
+		return nil, &AddressError{Account: account, Err: ErrAddressAlphabet}
+	}
+
+	// The address string is 260bits which doesn't fall on a
+	// byte boundary. pad with zeros to 280bits.
+	// (zeros are encoded as 1 in nano's 32bit alphabet)
+	key_b32nano := "1111" + address[0:52]
+	input_checksum := address[52:]
 
-	return nil, errors.New("invalid address format")
+	key_bytes, err := NanoEncoding.DecodeString(key_b32nano)
+	if err != nil {
+		return nil, &AddressError{Account: account, Err: ErrAddressAlphabet}
+	}
+	// strip off upper 24 bits (3 bytes). 20 padding was added by us,
+	// 4 is unused as account is 256 bits.
+	key_bytes = key_bytes[3:]
+
+	// nano checksum is calculated by hashing the key and reversing the bytes
+	if NanoEncoding.EncodeToString(GetAddressChecksum(key_bytes)) != input_checksum {
+		return nil, &AddressError{Account: account, Err: ErrAddressChecksum}
+	}
+	return key_bytes, nil
 }
 
 func GetAddressChecksum(pub ed25519.PublicKey) []byte {
@@ -88,6 +138,12 @@ func GetAddressChecksum(pub ed25519.PublicKey) []byte {
 }
 
 func PubKeyToAddress(pub ed25519.PublicKey) Account {
+	return PubKeyToAddressWithPrefix(pub, PrefixBanano)
+}
+
+// PubKeyToAddressWithPrefix encodes pub as an address starting with prefix,
+// usually one of the Prefix constants.
+func PubKeyToAddressWithPrefix(pub ed25519.PublicKey, prefix string) Account {
 	// Pubkey is 256bits, base32 must be multiple of 5 bits
 	// to encode properly.
 	// Pad the start with 0's and strip them off after base32 encoding
@@ -95,7 +151,7 @@ func PubKeyToAddress(pub ed25519.PublicKey) Account {
 	address := NanoEncoding.EncodeToString(padded)[4:]
 	checksum := NanoEncoding.EncodeToString(GetAddressChecksum(pub))
 
-	return Account("ban_" + address + checksum)
+	return Account(prefix + address + checksum)
 }
 
 func KeypairFromPrivateKey(private_key string) (ed25519.PublicKey, ed25519.PrivateKey) {
@@ -141,12 +197,34 @@ func KeypairFromSeed(seed io.Reader, index uint32) (ed25519.PublicKey, ed25519.P
 	return pub, priv, nil
 }
 
+// KeypairFromSecret derives the keys of the account a secret of keyType
+// belongs to, seeds give their first account.
+func KeypairFromSecret(keyType KeyType, secret string) (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	switch keyType {
+	case KeyTypeNone:
+		return nil, nil, ErrNoSecret
+	case KeyTypeSplit:
+		return nil, nil, ErrSplitKey
+	}
+	key, err := hex.DecodeString(secret)
+	if err != nil || len(key) != 32 {
+		return nil, nil, errors.New("secret must be 64 hex characters")
+	}
+	switch keyType {
+	case KeyTypeSeed:
+		return KeypairFromSeed(bytes.NewReader(key), 0)
+	case KeyTypeAdhoc:
+		pub, priv := KeypairFromPrivateKey(secret)
+		return pub, priv, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported key type %q", keyType)
+}
+
 // Generate a private key and the first public account key
 func GeneratePrivateKeyAndFirstPublicAddress() (string, Account, error) {
 	key := make([]byte, 32)
-	_, err := rand.Read(key)
+	err := ReadEntropy(key)
 	if err != nil {
-		log.Fatal("Could not get a crypto random value.")
 		return "", "", err
 	}
 
@@ -158,8 +236,25 @@ func GeneratePrivateKeyAndFirstPublicAddress() (string, Account, error) {
 	return hex.EncodeToString(key), account, nil
 }
 
+// Generate an ad-hoc private key and its public account key
+func GenerateAdhocKeyAndPublicAddress() (string, Account, error) {
+	key := make([]byte, 32)
+	err := ReadEntropy(key)
+	if err != nil {
+		return "", "", err
+	}
+	pubKey, _, err := ed25519.GenerateKey(bytes.NewReader(key))
+	if err != nil {
+		return "", "", err
+	}
+	account := PubKeyToAddress(pubKey)
+	// The private key is the secret the wallets import, the ed25519
+	// private key is it followed by the public key.
+	return hex.EncodeToString(key), account, nil
+}
+
 func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey) {
-	pubkey, privkey, err := ed25519.GenerateKey(nil)
+	pubkey, privkey, err := ed25519.GenerateKey(EntropySource())
 	if err != nil {
 		panic("Unable to generate ed25519 key")
 	}
@@ -3,6 +3,7 @@ package bananoutils_test
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"testing"
 
 	"github.com/steampoweredtaco/legion-van/bananoutils"
@@ -24,3 +25,77 @@ func TestKeypairFromSeed(t *testing.T) {
 	}
 
 }
+
+func TestGenerateAdhocKeyAndPublicAddress(t *testing.T) {
+	privateKey, account, err := bananoutils.GenerateAdhocKeyAndPublicAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(privateKey) != 64 {
+		t.Fatalf("expected a 32 byte hex private key, got %d characters", len(privateKey))
+	}
+	pub, _ := bananoutils.KeypairFromPrivateKey(privateKey)
+	if address := bananoutils.PubKeyToAddress(pub); address != account {
+		t.Errorf("expected %s to be equal to %s", address, account)
+	}
+}
+
+func TestSeedToMnemonic(t *testing.T) {
+	// test vectors from the BIP39 specification
+	vectors := []struct {
+		seed     string
+		mnemonic string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title",
+		},
+		{
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+		},
+		{
+			"3e141609b97933b66a060dcddc71fad1d91677db872031e85f4c015c5e7e8982",
+			"dignity pass list indicate nasty swamp pool script soccer toe leaf photo multiply desk host tomato cradle drill spread actor shine dismiss champion exotic",
+		},
+	}
+	for _, vector := range vectors {
+		seed, err := hex.DecodeString(vector.seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mnemonic, err := bananoutils.SeedToMnemonic(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mnemonic != vector.mnemonic {
+			t.Errorf("expected %s to be equal to %s", mnemonic, vector.mnemonic)
+		}
+		decoded, err := bananoutils.MnemonicToSeed(mnemonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded, seed) {
+			t.Errorf("expected %x to be equal to %s", decoded, vector.seed)
+		}
+	}
+}
+
+func TestMnemonicToSeedErrors(t *testing.T) {
+	_, err := bananoutils.MnemonicToSeed("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo")
+	if !errors.Is(err, bananoutils.ErrMnemonicChecksum) {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+	_, err = bananoutils.MnemonicToSeed("zoo zoo zoo")
+	if !errors.Is(err, bananoutils.ErrMnemonicLength) {
+		t.Errorf("expected length error, got %v", err)
+	}
+	_, err = bananoutils.MnemonicToSeed("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo banano")
+	if !errors.Is(err, bananoutils.ErrMnemonicWord) {
+		t.Errorf("expected word error, got %v", err)
+	}
+}
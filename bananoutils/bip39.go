@@ -0,0 +1,97 @@
+package bananoutils
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Banano wallets encode the 32 byte seed as BIP39 entropy, 256 bits of seed
+// plus an 8 bit checksum gives 24 words of 11 bits each.
+const (
+	mnemonicWords    = 24
+	mnemonicWordBits = 11
+	seedSize         = 32
+)
+
+var (
+	ErrMnemonicLength   = errors.New("mnemonic must be 24 words")
+	ErrMnemonicWord     = errors.New("mnemonic contains a word not in the BIP39 english word list")
+	ErrMnemonicChecksum = errors.New("invalid mnemonic checksum")
+)
+
+var (
+	bip39Words       = strings.Fields(bip39English)
+	bip39WordIndexes = make(map[string]int, len(bip39Words))
+)
+
+func init() {
+	for i, word := range bip39Words {
+		bip39WordIndexes[word] = i
+	}
+}
+
+// SeedToMnemonic encodes a 32 byte wallet seed as 24 BIP39 words.
+func SeedToMnemonic(seed []byte) (string, error) {
+	if len(seed) != seedSize {
+		return "", fmt.Errorf("seed must be %d bytes, got %d", seedSize, len(seed))
+	}
+	checksum := sha256.Sum256(seed)
+	// seed bits followed by the first 8 bits of the checksum
+	data := append(append(make([]byte, 0, seedSize+1), seed...), checksum[0])
+
+	words := make([]string, mnemonicWords)
+	for i := range words {
+		words[i] = bip39Words[readBits(data, i*mnemonicWordBits, mnemonicWordBits)]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToSeed decodes 24 BIP39 words back into a 32 byte wallet seed and
+// validates the checksum.
+func MnemonicToSeed(mnemonic string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) != mnemonicWords {
+		return nil, ErrMnemonicLength
+	}
+
+	data := make([]byte, seedSize+1)
+	for i, word := range words {
+		index, ok := bip39WordIndexes[word]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrMnemonicWord, word)
+		}
+		writeBits(data, i*mnemonicWordBits, mnemonicWordBits, index)
+	}
+
+	seed := data[:seedSize]
+	checksum := sha256.Sum256(seed)
+	if checksum[0] != data[seedSize] {
+		return nil, ErrMnemonicChecksum
+	}
+	return seed, nil
+}
+
+// readBits reads count bits starting at bit offset, most significant bit first.
+func readBits(data []byte, offset, count int) int {
+	value := 0
+	for i := offset; i < offset+count; i++ {
+		value <<= 1
+		if data[i/8]&(0x80>>uint(i%8)) != 0 {
+			value |= 1
+		}
+	}
+	return value
+}
+
+// writeBits writes the lower count bits of value starting at bit offset, most
+// significant bit first.
+func writeBits(data []byte, offset, count, value int) {
+	for i := 0; i < count; i++ {
+		if value&(1<<uint(count-1-i)) != 0 {
+			bit := offset + i
+			data[bit/8] |= 0x80 >> uint(bit%8)
+		}
+	}
+}
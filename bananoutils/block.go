@@ -0,0 +1,184 @@
+package bananoutils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/bbedward/crypto/ed25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+const stateBlockType = "state"
+
+// ZeroBlockHash is the previous hash of the first block of an account.
+const ZeroBlockHash = BlockHash("0000000000000000000000000000000000000000000000000000000000000000")
+
+// Every state block hash starts with 32 bytes that are zero except the last
+// one, which is the state block type (6) in the node.
+var stateBlockPreamble = func() []byte {
+	preamble := make([]byte, 32)
+	preamble[31] = 6
+	return preamble
+}()
+
+// maxBalance is the largest raw balance that fits in the 128 bits of a block.
+var maxBalance = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// StateBlock is a Banano state block in the json format of the node RPC with
+// json_block enabled. Balance is in raw as a decimal string.
+type StateBlock struct {
+	Type           string    `json:"type"`
+	Account        Account   `json:"account"`
+	Previous       BlockHash `json:"previous"`
+	Representative Account   `json:"representative"`
+	Balance        string    `json:"balance"`
+	Link           BlockHash `json:"link"`
+	LinkAsAccount  Account   `json:"link_as_account"`
+	Signature      Signature `json:"signature"`
+	Work           Work      `json:"work"`
+}
+
+// NewOpenBlock makes the first block of account, receiving the send block
+// sendHash which leaves the account with balance raw.
+func NewOpenBlock(account Account, representative Account, balance string, sendHash BlockHash) (*StateBlock, error) {
+	return newStateBlock(account, ZeroBlockHash, representative, balance, sendHash)
+}
+
+// NewChangeBlock makes a block that only changes the representative of an
+// already opened account, balance must stay the current balance.
+func NewChangeBlock(account Account, previous BlockHash, representative Account, balance string) (*StateBlock, error) {
+	return newStateBlock(account, previous, representative, balance, ZeroBlockHash)
+}
+
+func newStateBlock(account Account, previous BlockHash, representative Account, balance string, link BlockHash) (*StateBlock, error) {
+	block := &StateBlock{
+		Type:           stateBlockType,
+		Account:        account,
+		Previous:       previous,
+		Representative: representative,
+		Balance:        balance,
+		Link:           link,
+	}
+	linkBytes, err := hashBytes("link", link)
+	if err != nil {
+		return nil, err
+	}
+	block.LinkAsAccount = PubKeyToAddress(linkBytes)
+	// validates every field
+	_, err = block.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Hash calculates the canonical hash of the block, the signature and work
+// are not part of it.
+func (block *StateBlock) Hash() (BlockHash, error) {
+	if block.Type != stateBlockType {
+		return "", fmt.Errorf("unsupported block type %q", block.Type)
+	}
+	account, err := AddressToPub(block.Account)
+	if err != nil {
+		return "", fmt.Errorf("bad account: %w", err)
+	}
+	previous, err := hashBytes("previous", block.Previous)
+	if err != nil {
+		return "", err
+	}
+	representative, err := AddressToPub(block.Representative)
+	if err != nil {
+		return "", fmt.Errorf("bad representative: %w", err)
+	}
+	balance, err := balanceBytes(block.Balance)
+	if err != nil {
+		return "", err
+	}
+	link, err := hashBytes("link", block.Link)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := blake2b.New256(nil)
+	if err != nil {
+		panic("Unable to create hash")
+	}
+	hash.Write(stateBlockPreamble)
+	hash.Write(account)
+	hash.Write(previous)
+	hash.Write(representative)
+	hash.Write(balance)
+	hash.Write(link)
+	return BlockHashFromBytes(hash.Sum(nil)), nil
+}
+
+// Sign signs the block with the private key of its account.
+func (block *StateBlock) Sign(privateKey ed25519.PrivateKey) error {
+	account, err := AddressToPub(block.Account)
+	if err != nil {
+		return fmt.Errorf("bad account: %w", err)
+	}
+	if len(privateKey) != ed25519.PrivateKeySize || !bytes.Equal(privateKey[32:], account) {
+		return errors.New("private key does not belong to the block account")
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	block.Signature = hash.Sign(privateKey)
+	return nil
+}
+
+// SignExpanded is Sign for the expanded key of a combined split key account.
+func (block *StateBlock) SignExpanded(key *ExpandedKey) error {
+	account, err := AddressToPub(block.Account)
+	if err != nil {
+		return fmt.Errorf("bad account: %w", err)
+	}
+	if !bytes.Equal(key.PublicKey(), account) {
+		return errors.New("expanded key does not belong to the block account")
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	block.Signature = Signature(strings.ToUpper(hex.EncodeToString(key.Sign(hash.ToBytes()))))
+	return nil
+}
+
+// VerifySignature reports whether the block is signed by its account.
+func (block *StateBlock) VerifySignature() (bool, error) {
+	account, err := AddressToPub(block.Account)
+	if err != nil {
+		return false, fmt.Errorf("bad account: %w", err)
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		return false, err
+	}
+	signature, err := hex.DecodeString(string(block.Signature))
+	if err != nil {
+		return false, fmt.Errorf("bad signature: %w", err)
+	}
+	return ed25519.Verify(account, hash.ToBytes(), signature), nil
+}
+
+func hashBytes(name string, hash BlockHash) ([]byte, error) {
+	data, err := hex.DecodeString(string(hash))
+	if err != nil || len(data) != 32 {
+		return nil, fmt.Errorf("%s must be 64 hex characters: %q", name, hash)
+	}
+	return data, nil
+}
+
+func balanceBytes(balance string) ([]byte, error) {
+	raw, ok := new(big.Int).SetString(strings.TrimSpace(balance), 10)
+	if !ok || raw.Sign() < 0 || raw.Cmp(maxBalance) > 0 {
+		return nil, fmt.Errorf("balance must be a raw amount between 0 and 2^128-1: %q", balance)
+	}
+	return raw.FillBytes(make([]byte, 16)), nil
+}
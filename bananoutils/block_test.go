@@ -0,0 +1,131 @@
+package bananoutils_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+const (
+	testRepresentative = bananoutils.Account("ban_3tacocatezozswnu8xkh66qa1dbcdujktzmfpdj7ax66wtfrio6h5sxikkep")
+	testSendHash       = bananoutils.BlockHash("5A3A2C6F1E0B1D3C4B5A69788796A5B4C3D2E1F00112233445566778899AABBC")
+)
+
+func testSeedKeypair(t *testing.T) (bananoutils.Account, []byte, []byte) {
+	t.Helper()
+	seed, err := hex.DecodeString("deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, err := bananoutils.KeypairFromSeed(bytes.NewReader(seed), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bananoutils.PubKeyToAddress(pub), pub, priv
+}
+
+func TestOpenBlockHashAndSign(t *testing.T) {
+	account, _, priv := testSeedKeypair(t)
+	block, err := bananoutils.NewOpenBlock(account, testRepresentative, "1000000000000000000000000000000", testSendHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// calculated independently with python's hashlib.blake2b
+	expectedHash := bananoutils.BlockHash("7D897C260CE42AFF3AAEA09861C73B18B85C12251EB545FB2BBE0B16F55F047F")
+	if hash != expectedHash {
+		t.Errorf("expected %s to be equal to %s", hash, expectedHash)
+	}
+
+	err = block.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := block.VerifySignature()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("expected the signature to be valid")
+	}
+
+	block.Balance = "1"
+	valid, err = block.VerifySignature()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("expected the signature to be invalid after changing the balance")
+	}
+}
+
+func TestStateBlockJSON(t *testing.T) {
+	account, _, priv := testSeedKeypair(t)
+	block, err := bananoutils.NewChangeBlock(account, testSendHash, testRepresentative, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = block.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]string
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"type", "account", "previous", "representative", "balance", "link", "link_as_account", "signature", "work"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("expected %s in the block json", field)
+		}
+	}
+	if fields["link"] != string(bananoutils.ZeroBlockHash) || fields["link_as_account"] != "ban_1111111111111111111111111111111111111111111111111111hifc8npp" {
+		t.Errorf("expected a zero link for a change block, got %s %s", fields["link"], fields["link_as_account"])
+	}
+
+	var decoded bananoutils.StateBlock
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := decoded.VerifySignature()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("expected the decoded block signature to be valid")
+	}
+}
+
+func TestStateBlockRejectsBadFields(t *testing.T) {
+	account, _, priv := testSeedKeypair(t)
+	_, err := bananoutils.NewOpenBlock(account, testRepresentative, "-1", testSendHash)
+	if err == nil {
+		t.Error("expected an error for a negative balance")
+	}
+	_, err = bananoutils.NewOpenBlock(account, testRepresentative, "340282366920938463463374607431768211456", testSendHash)
+	if err == nil {
+		t.Error("expected an error for a balance over 128 bits")
+	}
+	_, err = bananoutils.NewOpenBlock(account, testRepresentative, "1", "beef")
+	if err == nil {
+		t.Error("expected an error for a short link")
+	}
+	block, err := bananoutils.NewOpenBlock(testRepresentative, account, "1", testSendHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Sign(priv) == nil {
+		t.Error("expected an error signing with the key of another account")
+	}
+}
@@ -0,0 +1,113 @@
+package bananoutils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// ErrEntropyRepeated is returned by CheckEntropy for sources that give back
+// the same data more than once, keys made from them can be guessed.
+var ErrEntropyRepeated = errors.New("entropy source returned repeated data")
+
+// how many blocks of key size CheckEntropy compares.
+const entropyCheckBlocks = 64
+
+var (
+	entropyMu     sync.RWMutex
+	entropySource io.Reader = rand.Reader
+)
+
+// SetEntropySource changes where new keys get their randomness from, nil
+// goes back to crypto/rand. Sources other than crypto/rand are only meant for
+// tests, reads from them are serialized so they do not need to be safe for
+// concurrent use.
+func SetEntropySource(source io.Reader) {
+	entropyMu.Lock()
+	defer entropyMu.Unlock()
+	if source == nil {
+		entropySource = rand.Reader
+		return
+	}
+	entropySource = &lockedReader{reader: source}
+}
+
+// EntropySource returns the source new keys are made from.
+func EntropySource() io.Reader {
+	entropyMu.RLock()
+	defer entropyMu.RUnlock()
+	return entropySource
+}
+
+// ReadEntropy fills b from the entropy source.
+func ReadEntropy(b []byte) error {
+	_, err := io.ReadFull(EntropySource(), b)
+	if err != nil {
+		return fmt.Errorf("could not read entropy: %w", err)
+	}
+	return nil
+}
+
+// CheckEntropy reads a number of key sized blocks from source and fails if any
+// block repeats or is a single repeated byte, a sign of a broken or stubbed
+// out random source. It cannot prove a source is random, only catch the
+// obviously bad ones before any keys are made from them.
+func CheckEntropy(source io.Reader) error {
+	seen := make(map[string]bool, entropyCheckBlocks)
+	for i := 0; i < entropyCheckBlocks; i++ {
+		block := make([]byte, 32)
+		_, err := io.ReadFull(source, block)
+		if err != nil {
+			return fmt.Errorf("could not read entropy: %w", err)
+		}
+		if bytes.Count(block, block[:1]) == len(block) {
+			return fmt.Errorf("%w: block of only %#02x", ErrEntropyRepeated, block[0])
+		}
+		if seen[string(block)] {
+			return fmt.Errorf("%w: block %d was seen before", ErrEntropyRepeated, i)
+		}
+		seen[string(block)] = true
+	}
+	return nil
+}
+
+// NewDeterministicEntropy returns an endless stream of bytes that only depends
+// on seed, the ChaCha20 key stream of its sha256. Anyone with the seed can
+// make every key, so it is only fit for reproducible tests.
+func NewDeterministicEntropy(seed []byte) io.Reader {
+	key := sha256.Sum256(seed)
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], make([]byte, chacha20.NonceSize))
+	if err != nil {
+		panic(err)
+	}
+	return &keyStream{cipher: cipher}
+}
+
+type keyStream struct {
+	cipher *chacha20.Cipher
+}
+
+func (stream *keyStream) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	stream.cipher.XORKeyStream(b, b)
+	return len(b), nil
+}
+
+type lockedReader struct {
+	mu     sync.Mutex
+	reader io.Reader
+}
+
+func (r *lockedReader) Read(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reader.Read(b)
+}
@@ -0,0 +1,94 @@
+package bananoutils_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// repeatReader returns the same block over and over.
+type repeatReader struct {
+	block []byte
+	i     int
+}
+
+func (r *repeatReader) Read(b []byte) (int, error) {
+	for n := range b {
+		b[n] = r.block[r.i%len(r.block)]
+		r.i++
+	}
+	return len(b), nil
+}
+
+func TestCheckEntropy(t *testing.T) {
+	err := bananoutils.CheckEntropy(rand.Reader)
+	if err != nil {
+		t.Errorf("crypto/rand failed the check: %s", err)
+	}
+	err = bananoutils.CheckEntropy(bananoutils.NewDeterministicEntropy([]byte("test")))
+	if err != nil {
+		t.Errorf("deterministic entropy failed the check: %s", err)
+	}
+
+	bad := map[string]io.Reader{
+		"zeros":         bytes.NewReader(make([]byte, 4096)),
+		"same byte":     &repeatReader{block: []byte{0x42}},
+		"repeated key":  &repeatReader{block: bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7, 8}, 4)},
+		"later repeats": io.MultiReader(bytes.NewReader(bytes.Repeat([]byte{9, 8, 7, 6}, 8)), &repeatReader{block: bytes.Repeat([]byte{9, 8, 7, 6}, 8)}),
+	}
+	for name, source := range bad {
+		err = bananoutils.CheckEntropy(source)
+		if !errors.Is(err, bananoutils.ErrEntropyRepeated) {
+			t.Errorf("%s: expected ErrEntropyRepeated, got %v", name, err)
+		}
+	}
+	err = bananoutils.CheckEntropy(bytes.NewReader([]byte{1, 2, 3}))
+	if err == nil {
+		t.Error("expected an error for a source that runs out")
+	}
+}
+
+func TestDeterministicEntropy(t *testing.T) {
+	defer bananoutils.SetEntropySource(nil)
+	generate := func(seed string) []bananoutils.Account {
+		bananoutils.SetEntropySource(bananoutils.NewDeterministicEntropy([]byte(seed)))
+		var accounts []bananoutils.Account
+		for i := 0; i < 3; i++ {
+			_, account, err := bananoutils.GeneratePrivateKeyAndFirstPublicAddress()
+			if err != nil {
+				t.Fatal(err)
+			}
+			accounts = append(accounts, account)
+			_, account, err = bananoutils.GenerateAdhocKeyAndPublicAddress()
+			if err != nil {
+				t.Fatal(err)
+			}
+			accounts = append(accounts, account)
+		}
+		return accounts
+	}
+	first, again, other := generate("taco"), generate("taco"), generate("burrito")
+	for i := range first {
+		if first[i] != again[i] {
+			t.Errorf("account %d differs for the same seed: %s and %s", i, first[i], again[i])
+		}
+		if first[i] == other[i] {
+			t.Errorf("account %d is the same for different seeds", i)
+		}
+	}
+}
+
+func TestGenerateReportsEntropyErrors(t *testing.T) {
+	defer bananoutils.SetEntropySource(nil)
+	bananoutils.SetEntropySource(bytes.NewReader(nil))
+	if _, _, err := bananoutils.GeneratePrivateKeyAndFirstPublicAddress(); err == nil {
+		t.Error("expected an error from an empty entropy source")
+	}
+	if _, _, err := bananoutils.GenerateAdhocKeyAndPublicAddress(); err == nil {
+		t.Error("expected an error from an empty entropy source")
+	}
+}
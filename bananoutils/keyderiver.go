@@ -0,0 +1,122 @@
+package bananoutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"filippo.io/edwards25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+// KeySize is the length of seeds, private keys and public keys in bytes.
+const KeySize = 32
+
+var ErrKeyBatchSize = errors.New("key batch buffers must hold the same number of 32 byte keys")
+
+// KeyDeriver makes public keys for many secrets without allocating. It keeps
+// its hasher and buffers between keys, so one KeyDeriver must not be used by
+// more than one goroutine at a time.
+//
+// The keys are the same ones KeypairFromSeed and KeypairFromPrivateKey give,
+// the public key is the clamped blake2b-512 of the private key multiplied
+// with the base point.
+type KeyDeriver struct {
+	seedHash hash.Hash
+	index    [4]byte
+	key      [KeySize]byte
+	scalar   edwards25519.Scalar
+	point    edwards25519.Point
+	base     *edwards25519.Point
+}
+
+func NewKeyDeriver() *KeyDeriver {
+	seedHash, err := blake2b.New256(nil)
+	if err != nil {
+		panic("Unable to create hash")
+	}
+	return &KeyDeriver{seedHash: seedHash}
+}
+
+// AdhocPublicKey writes the public key of the ad-hoc private key to pub.
+func (deriver *KeyDeriver) AdhocPublicKey(pub, privateKey []byte) {
+	digest := blake2b.Sum512(privateKey[:KeySize])
+	// only fails for a slice that is not 32 bytes
+	_, _ = deriver.scalar.SetBytesWithClamping(digest[:KeySize])
+	deriver.point.ScalarBaseMult(&deriver.scalar)
+	copy(pub[:KeySize], deriver.point.Bytes())
+}
+
+// SetSplitBase sets the public key partial keys are added to for
+// KeyTypeSplit, see CombineSplitKey.
+func (deriver *KeyDeriver) SetSplitBase(pub []byte) error {
+	base, err := new(edwards25519.Point).SetBytes(pub)
+	if err != nil {
+		return ErrInvalidPublicKey
+	}
+	deriver.base = base
+	return nil
+}
+
+// SplitPublicKey writes the public key of partial added to the split base to
+// pub.
+func (deriver *KeyDeriver) SplitPublicKey(pub, partial []byte) {
+	// only fails for a slice that is not 32 bytes
+	_, _ = deriver.scalar.SetBytesWithClamping(partial[:KeySize])
+	deriver.point.ScalarBaseMult(&deriver.scalar)
+	deriver.point.Add(&deriver.point, deriver.base)
+	copy(pub[:KeySize], deriver.point.Bytes())
+}
+
+// SeedPublicKey writes the public key of the account at index of seed to pub.
+func (deriver *KeyDeriver) SeedPublicKey(pub, seed []byte, index uint32) {
+	binary.BigEndian.PutUint32(deriver.index[:], index)
+	deriver.seedHash.Reset()
+	deriver.seedHash.Write(seed[:KeySize])
+	deriver.seedHash.Write(deriver.index[:])
+	deriver.seedHash.Sum(deriver.key[:0])
+	deriver.AdhocPublicKey(pub, deriver.key[:])
+}
+
+// PublicKeys writes the public key of every secret to pubs, secrets and pubs
+// are keys of KeySize bytes back to back. Seeds give their first account and
+// split keys the account they make with the split base.
+func (deriver *KeyDeriver) PublicKeys(keyType KeyType, pubs, secrets []byte) error {
+	if len(secrets)%KeySize != 0 || len(pubs) != len(secrets) {
+		return ErrKeyBatchSize
+	}
+	switch keyType {
+	case KeyTypeSeed:
+		for i := 0; i < len(secrets); i += KeySize {
+			deriver.SeedPublicKey(pubs[i:i+KeySize], secrets[i:i+KeySize], 0)
+		}
+	case KeyTypeAdhoc:
+		for i := 0; i < len(secrets); i += KeySize {
+			deriver.AdhocPublicKey(pubs[i:i+KeySize], secrets[i:i+KeySize])
+		}
+	case KeyTypeSplit:
+		if deriver.base == nil {
+			return errors.New("split keys need SetSplitBase first")
+		}
+		for i := 0; i < len(secrets); i += KeySize {
+			deriver.SplitPublicKey(pubs[i:i+KeySize], secrets[i:i+KeySize])
+		}
+	default:
+		return fmt.Errorf("unsupported key type %q", keyType)
+	}
+	return nil
+}
+
+// Generate fills secrets with new keys from the entropy source and pubs with
+// their public keys, see PublicKeys.
+func (deriver *KeyDeriver) Generate(keyType KeyType, pubs, secrets []byte) error {
+	if len(secrets)%KeySize != 0 || len(pubs) != len(secrets) {
+		return ErrKeyBatchSize
+	}
+	err := ReadEntropy(secrets)
+	if err != nil {
+		return err
+	}
+	return deriver.PublicKeys(keyType, pubs, secrets)
+}
@@ -0,0 +1,125 @@
+package bananoutils_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+func TestKeyDeriverMatchesKeypair(t *testing.T) {
+	defer bananoutils.SetEntropySource(nil)
+	bananoutils.SetEntropySource(bananoutils.NewDeterministicEntropy([]byte("deriver")))
+
+	const keys = 16
+	deriver := bananoutils.NewKeyDeriver()
+	for _, keyType := range []bananoutils.KeyType{bananoutils.KeyTypeSeed, bananoutils.KeyTypeAdhoc} {
+		secrets := make([]byte, keys*bananoutils.KeySize)
+		pubs := make([]byte, keys*bananoutils.KeySize)
+		err := deriver.Generate(keyType, pubs, secrets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(secrets); i += bananoutils.KeySize {
+			secret := hex.EncodeToString(secrets[i : i+bananoutils.KeySize])
+			want, _, err := bananoutils.KeypairFromSecret(keyType, secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(pubs[i:i+bananoutils.KeySize], want) {
+				t.Errorf("%s key %d: expected %x, got %x", keyType, i/bananoutils.KeySize, []byte(want), pubs[i:i+bananoutils.KeySize])
+			}
+		}
+	}
+
+	seed, _ := hex.DecodeString("deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	pub := make([]byte, bananoutils.KeySize)
+	deriver.SeedPublicKey(pub, seed, 0)
+	if address := bananoutils.PubKeyToAddress(pub); address != testAddress {
+		t.Errorf("expected %s, got %s", testAddress, address)
+	}
+
+	err := deriver.PublicKeys(bananoutils.KeyTypeSeed, make([]byte, 32), make([]byte, 64))
+	if !errors.Is(err, bananoutils.ErrKeyBatchSize) {
+		t.Errorf("expected ErrKeyBatchSize, got %v", err)
+	}
+	err = deriver.PublicKeys(bananoutils.KeyTypeNone, make([]byte, 32), make([]byte, 32))
+	if err == nil {
+		t.Error("expected an error for a key type without secrets")
+	}
+}
+
+func TestKeyDeriverDoesNotAllocate(t *testing.T) {
+	deriver := bananoutils.NewKeyDeriver()
+	secrets := bytes.Repeat([]byte{7}, 8*bananoutils.KeySize)
+	pubs := make([]byte, len(secrets))
+	allocs := testing.AllocsPerRun(10, func() {
+		deriver.PublicKeys(bananoutils.KeyTypeSeed, pubs, secrets)
+		deriver.PublicKeys(bananoutils.KeyTypeAdhoc, pubs, secrets)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %.0f per batch", allocs)
+	}
+}
+
+// reportKeyRate adds a keys/s metric so the benchmarks can be compared by
+// keys instead of by call.
+func reportKeyRate(b *testing.B, started time.Time, keys int) {
+	b.ReportMetric(float64(keys)/time.Since(started).Seconds(), "keys/s")
+}
+
+func BenchmarkGeneratePrivateKeyAndFirstPublicAddress(b *testing.B) {
+	b.ReportAllocs()
+	started := time.Now()
+	for i := 0; i < b.N; i++ {
+		_, _, err := bananoutils.GeneratePrivateKeyAndFirstPublicAddress()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportKeyRate(b, started, b.N)
+}
+
+func BenchmarkKeypairFromSeed(b *testing.B) {
+	seed := bytes.Repeat([]byte{7}, bananoutils.KeySize)
+	b.ReportAllocs()
+	started := time.Now()
+	for i := 0; i < b.N; i++ {
+		_, _, err := bananoutils.KeypairFromSeed(bytes.NewReader(seed), 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportKeyRate(b, started, b.N)
+}
+
+func BenchmarkKeyDeriverSeedPublicKey(b *testing.B) {
+	deriver := bananoutils.NewKeyDeriver()
+	seed := bytes.Repeat([]byte{7}, bananoutils.KeySize)
+	pub := make([]byte, bananoutils.KeySize)
+	b.ReportAllocs()
+	started := time.Now()
+	for i := 0; i < b.N; i++ {
+		deriver.SeedPublicKey(pub, seed, 0)
+	}
+	reportKeyRate(b, started, b.N)
+}
+
+func BenchmarkKeyDeriverGenerate(b *testing.B) {
+	const keys = 256
+	deriver := bananoutils.NewKeyDeriver()
+	secrets := make([]byte, keys*bananoutils.KeySize)
+	pubs := make([]byte, keys*bananoutils.KeySize)
+	b.ReportAllocs()
+	started := time.Now()
+	for i := 0; i < b.N; i++ {
+		err := deriver.Generate(bananoutils.KeyTypeSeed, pubs, secrets)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportKeyRate(b, started, b.N*keys)
+}
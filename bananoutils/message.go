@@ -0,0 +1,40 @@
+package bananoutils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/bbedward/crypto/ed25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+// messagePrefix is hashed in front of every signed message so an ownership
+// proof can never be a valid signature for a block hash.
+const messagePrefix = "Banano Signed Message:\n"
+
+func messageHash(message string) []byte {
+	hash := blake2b.Sum256([]byte(messagePrefix + message))
+	return hash[:]
+}
+
+// SignMessage signs message with the private key of an account to prove
+// control of the account without sharing its key.
+func SignMessage(privateKey ed25519.PrivateKey, message string) Signature {
+	sig := hex.EncodeToString(ed25519.Sign(privateKey, messageHash(message)))
+	return Signature(strings.ToUpper(sig))
+}
+
+// VerifyMessage reports whether signature was made by the key of account for
+// message.
+func VerifyMessage(account Account, message string, signature Signature) (bool, error) {
+	pub, err := AddressToPub(account)
+	if err != nil {
+		return false, fmt.Errorf("bad account: %w", err)
+	}
+	sig, err := hex.DecodeString(string(signature))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false, fmt.Errorf("signature must be %d hex characters", ed25519.SignatureSize*2)
+	}
+	return ed25519.Verify(pub, messageHash(message), sig), nil
+}
@@ -0,0 +1,63 @@
+package bananoutils_test
+
+import (
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+func TestSignAndVerifyMessage(t *testing.T) {
+	account, _, priv := testSeedKeypair(t)
+	message := "this monKey belongs to taco"
+	signature := bananoutils.SignMessage(priv, message)
+
+	valid, err := bananoutils.VerifyMessage(account, message, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Fatal("signature of message is not valid")
+	}
+
+	valid, err = bananoutils.VerifyMessage(account, message+".", signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("signature is valid for a different message")
+	}
+
+	valid, err = bananoutils.VerifyMessage(testRepresentative, message, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("signature is valid for a different account")
+	}
+
+	_, err = bananoutils.VerifyMessage(account, message, signature[:10])
+	if err == nil {
+		t.Error("expected an error for a short signature")
+	}
+}
+
+func TestMessageSignatureIsNotBlockSignature(t *testing.T) {
+	account, _, priv := testSeedKeypair(t)
+	block, err := bananoutils.NewOpenBlock(account, testRepresentative, "1", testSendHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signing the raw bytes of a block hash as a message must not sign the block.
+	block.Signature = bananoutils.SignMessage(priv, string(hash.ToBytes()))
+	valid, err := block.VerifySignature()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("message signature is valid as a block signature")
+	}
+}
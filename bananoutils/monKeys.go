@@ -36,7 +36,11 @@ func GrabMonkey(ctx context.Context, publicAddr Account, format legion.ImageForm
 	addressBuilder.WriteString(string(publicAddr))
 	// svg is friendlier on the server, so do conversion if needed client side
 	addressBuilder.WriteString("?format=svg")
-	response, err := http.Get(addressBuilder.String())
+	request, err := http.NewRequestWithContext(ctx, "GET", addressBuilder.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not get monkey %w", err)
+	}
+	response, err := http.DefaultClient.Do(request)
 	if err != nil {
 		return nil, fmt.Errorf("could not get monkey %w", err)
 	}
@@ -0,0 +1,163 @@
+/*
+rpc package is a small client for the Banano node RPC actions legion-van needs
+to check found accounts and publish blocks prepared for them.
+*/
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// ErrAccountNotFound is returned for accounts that have never been opened.
+var ErrAccountNotFound = errors.New("account not found")
+
+// NodeError is an error message returned by the node.
+type NodeError struct {
+	Action  string
+	Message string
+}
+
+func (err *NodeError) Error() string {
+	return fmt.Sprintf("node %s failed: %s", err.Action, err.Message)
+}
+
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient makes a client for the node RPC at url, httpClient may be nil to
+// use the default client.
+func NewClient(url string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: url, httpClient: httpClient}
+}
+
+type AccountInfo struct {
+	Frontier            bananoutils.BlockHash `json:"frontier"`
+	OpenBlock           bananoutils.BlockHash `json:"open_block"`
+	RepresentativeBlock bananoutils.BlockHash `json:"representative_block"`
+	Balance             string                `json:"balance"`
+	ModifiedTimestamp   string                `json:"modified_timestamp"`
+	BlockCount          string                `json:"block_count"`
+	Representative      bananoutils.Account   `json:"representative"`
+}
+
+type AccountBalance struct {
+	Balance string `json:"balance"`
+	Pending string `json:"pending"`
+}
+
+// AccountInfo returns the ledger state of an account, ErrAccountNotFound if
+// it was never opened.
+func (c *Client) AccountInfo(ctx context.Context, account bananoutils.Account) (info AccountInfo, err error) {
+	err = c.call(ctx, map[string]interface{}{
+		"action":         "account_info",
+		"account":        account,
+		"representative": "true",
+	}, &info)
+	return
+}
+
+// AccountBalance returns the balance and pending balance of an account in raw.
+func (c *Client) AccountBalance(ctx context.Context, account bananoutils.Account) (balance AccountBalance, err error) {
+	err = c.call(ctx, map[string]interface{}{
+		"action":  "account_balance",
+		"account": account,
+	}, &balance)
+	return
+}
+
+// AccountRepresentative returns the representative of an opened account.
+func (c *Client) AccountRepresentative(ctx context.Context, account bananoutils.Account) (bananoutils.Account, error) {
+	var response struct {
+		Representative bananoutils.Account `json:"representative"`
+	}
+	err := c.call(ctx, map[string]interface{}{
+		"action":  "account_representative",
+		"account": account,
+	}, &response)
+	return response.Representative, err
+}
+
+// Process publishes a signed block with work, subtype is open, change,
+// receive or send. The node returns the hash of the accepted block.
+func (c *Client) Process(ctx context.Context, block *bananoutils.StateBlock, subtype string) (bananoutils.BlockHash, error) {
+	var response struct {
+		Hash bananoutils.BlockHash `json:"hash"`
+	}
+	err := c.call(ctx, map[string]interface{}{
+		"action":     "process",
+		"json_block": "true",
+		"subtype":    subtype,
+		"block":      block,
+	}, &response)
+	return response.Hash, err
+}
+
+// IsUnopened reports whether account has no blocks in the ledger yet.
+func (c *Client) IsUnopened(ctx context.Context, account bananoutils.Account) (bool, error) {
+	_, err := c.AccountInfo(ctx, account)
+	if errors.Is(err, ErrAccountNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func (c *Client) call(ctx context.Context, request map[string]interface{}, response interface{}) error {
+	action, _ := request["action"].(string)
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("could not marshal %s request: %w", action, err)
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpResponse, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		return fmt.Errorf("could not call node %s: %w", action, err)
+	}
+	defer httpResponse.Body.Close()
+	data, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return fmt.Errorf("could not read node %s response: %w", action, err)
+	}
+	if httpResponse.StatusCode != 200 {
+		return fmt.Errorf("non 200 error returned by node %s (%d %s)", action, httpResponse.StatusCode, httpResponse.Status)
+	}
+
+	// The node reports failures as a 200 with an error field.
+	var nodeError struct {
+		Error string `json:"error"`
+	}
+	err = json.Unmarshal(data, &nodeError)
+	if err != nil {
+		return fmt.Errorf("could not parse node %s response: %w", action, err)
+	}
+	if nodeError.Error != "" {
+		if nodeError.Error == "Account not found" {
+			return ErrAccountNotFound
+		}
+		return &NodeError{Action: action, Message: nodeError.Error}
+	}
+	err = json.Unmarshal(data, response)
+	if err != nil {
+		return fmt.Errorf("could not parse node %s response: %w", action, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/bananoutils/rpc"
+	"github.com/steampoweredtaco/legion-van/bananoutils/rpc/rpctest"
+)
+
+const (
+	testRepresentative = bananoutils.Account("ban_3tacocatezozswnu8xkh66qa1dbcdujktzmfpdj7ax66wtfrio6h5sxikkep")
+	testThreshold      = 0xff00000000000000
+)
+
+func newTestNode(t *testing.T) (*rpctest.FakeNode, *rpc.Client) {
+	t.Helper()
+	node := rpctest.NewFakeNode()
+	node.WorkThreshold = testThreshold
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+	return node, rpc.NewClient(server.URL, server.Client())
+}
+
+func TestOpenAndChangeRepresentative(t *testing.T) {
+	ctx := context.Background()
+	node, client := newTestNode(t)
+	pub, priv := bananoutils.GenerateKey()
+	account := bananoutils.PubKeyToAddress(pub)
+
+	unopened, err := client.IsUnopened(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unopened {
+		t.Fatal("expected a fresh account to be unopened")
+	}
+
+	sendHash := node.AddPending(account, "1000")
+	balance, err := client.AccountBalance(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Balance != "0" || balance.Pending != "1000" {
+		t.Errorf("expected 0 balance and 1000 pending, got %+v", balance)
+	}
+
+	open, err := bananoutils.NewOpenBlock(account, testRepresentative, "1000", sendHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = open.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = open.GenerateWork(ctx, testThreshold, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openHash, err := client.Process(ctx, open, "open")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := client.AccountInfo(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Frontier != openHash || info.Balance != "1000" || info.Representative != testRepresentative {
+		t.Errorf("unexpected account info after open %+v", info)
+	}
+
+	newRepresentative := bananoutils.PubKeyToAddress(pub)
+	change, err := bananoutils.NewChangeBlock(account, openHash, newRepresentative, "1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = change.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = change.GenerateWork(ctx, testThreshold, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Process(ctx, change, "change")
+	if err != nil {
+		t.Fatal(err)
+	}
+	representative, err := client.AccountRepresentative(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if representative != newRepresentative {
+		t.Errorf("expected %s to be equal to %s", representative, newRepresentative)
+	}
+}
+
+func TestProcessRejectsBadBlocks(t *testing.T) {
+	ctx := context.Background()
+	node, client := newTestNode(t)
+	pub, priv := bananoutils.GenerateKey()
+	account := bananoutils.PubKeyToAddress(pub)
+	sendHash := node.AddPending(account, "1000")
+
+	open, err := bananoutils.NewOpenBlock(account, testRepresentative, "1000", sendHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = open.GenerateWork(ctx, testThreshold, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Process(ctx, open, "open")
+	var nodeError *rpc.NodeError
+	if !errors.As(err, &nodeError) || nodeError.Message != "Bad signature" {
+		t.Errorf("expected a bad signature error, got %v", err)
+	}
+
+	err = open.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open.Balance = "2000"
+	err = open.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Process(ctx, open, "open")
+	if err == nil {
+		t.Error("expected opening with more than was sent to fail")
+	}
+
+	_, err = client.AccountRepresentative(ctx, account)
+	if !errors.Is(err, rpc.ErrAccountNotFound) {
+		t.Errorf("expected account not found, got %v", err)
+	}
+}
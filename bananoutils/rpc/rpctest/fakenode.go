@@ -0,0 +1,208 @@
+/*
+rpctest package has a stand in Banano node for tests, it keeps a tiny ledger in
+memory and answers the same RPC actions the rpc client uses.
+*/
+package rpctest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+type fakeAccount struct {
+	frontier       bananoutils.BlockHash
+	openBlock      bananoutils.BlockHash
+	representative bananoutils.Account
+	balance        string
+	blockCount     int
+}
+
+type pendingSend struct {
+	destination bananoutils.Account
+	amount      string
+}
+
+// FakeNode is an http.Handler that acts like a node RPC for a small in memory
+// ledger. Blocks are checked for signature, work and balance like a node would.
+type FakeNode struct {
+	// WorkThreshold processed blocks must reach, lower it to keep tests fast.
+	WorkThreshold uint64
+
+	mu       sync.Mutex
+	accounts map[bananoutils.Account]*fakeAccount
+	pending  map[bananoutils.BlockHash]pendingSend
+}
+
+func NewFakeNode() *FakeNode {
+	return &FakeNode{
+		WorkThreshold: bananoutils.DefaultWorkThreshold,
+		accounts:      make(map[bananoutils.Account]*fakeAccount),
+		pending:       make(map[bananoutils.BlockHash]pendingSend),
+	}
+}
+
+// AddAccount puts an already opened account in the ledger.
+func (node *FakeNode) AddAccount(account bananoutils.Account, representative bananoutils.Account, balance string) bananoutils.BlockHash {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	frontier := randomHash()
+	node.accounts[account] = &fakeAccount{
+		frontier:       frontier,
+		openBlock:      frontier,
+		representative: representative,
+		balance:        balance,
+		blockCount:     1,
+	}
+	return frontier
+}
+
+// AddPending makes a send of amount raw to destination that can be received
+// with an open block, the hash of the send block is returned.
+func (node *FakeNode) AddPending(destination bananoutils.Account, amount string) bananoutils.BlockHash {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	hash := randomHash()
+	node.pending[hash] = pendingSend{destination: destination, amount: amount}
+	return hash
+}
+
+func (node *FakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Action  string                  `json:"action"`
+		Account bananoutils.Account     `json:"account"`
+		Subtype string                  `json:"subtype"`
+		Block   *bananoutils.StateBlock `json:"block"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		reply(w, map[string]string{"error": "Unable to parse JSON"})
+		return
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	switch request.Action {
+	case "account_info":
+		account, ok := node.accounts[request.Account]
+		if !ok {
+			reply(w, map[string]string{"error": "Account not found"})
+			return
+		}
+		reply(w, map[string]string{
+			"frontier":             string(account.frontier),
+			"open_block":           string(account.openBlock),
+			"representative_block": string(account.openBlock),
+			"balance":              account.balance,
+			"modified_timestamp":   "1624000000",
+			"block_count":          strconv.Itoa(account.blockCount),
+			"representative":       string(account.representative),
+		})
+	case "account_balance":
+		balance, pending := "0", big.NewInt(0)
+		if account, ok := node.accounts[request.Account]; ok {
+			balance = account.balance
+		}
+		for _, send := range node.pending {
+			if send.destination == request.Account {
+				amount, _ := new(big.Int).SetString(send.amount, 10)
+				pending.Add(pending, amount)
+			}
+		}
+		reply(w, map[string]string{"balance": balance, "pending": pending.String()})
+	case "account_representative":
+		account, ok := node.accounts[request.Account]
+		if !ok {
+			reply(w, map[string]string{"error": "Account not found"})
+			return
+		}
+		reply(w, map[string]string{"representative": string(account.representative)})
+	case "process":
+		hash, message := node.process(request.Block, request.Subtype)
+		if message != "" {
+			reply(w, map[string]string{"error": message})
+			return
+		}
+		reply(w, map[string]string{"hash": string(hash)})
+	default:
+		reply(w, map[string]string{"error": "Unknown command"})
+	}
+}
+
+// process checks and applies a block, returning the node error message if it
+// is rejected.
+func (node *FakeNode) process(block *bananoutils.StateBlock, subtype string) (bananoutils.BlockHash, string) {
+	if block == nil {
+		return "", "Block is invalid"
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		return "", "Block is invalid"
+	}
+	valid, err := block.VerifySignature()
+	if err != nil || !valid {
+		return "", "Bad signature"
+	}
+	valid, err = block.ValidateWork(node.WorkThreshold)
+	if err != nil || !valid {
+		return "", "Block work is less than threshold"
+	}
+
+	account, opened := node.accounts[block.Account]
+	switch subtype {
+	case "open":
+		if opened || block.Previous != bananoutils.ZeroBlockHash {
+			return "", "Fork"
+		}
+		send, ok := node.pending[block.Link]
+		if !ok || send.destination != block.Account {
+			return "", "Gap source block"
+		}
+		if send.amount != block.Balance {
+			return "", "Balance and amount delta do not match"
+		}
+		delete(node.pending, block.Link)
+		node.accounts[block.Account] = &fakeAccount{
+			frontier:       hash,
+			openBlock:      hash,
+			representative: block.Representative,
+			balance:        block.Balance,
+			blockCount:     1,
+		}
+	case "change":
+		if !opened {
+			return "", "Gap previous block"
+		}
+		if block.Previous != account.frontier {
+			return "", "Fork"
+		}
+		if block.Balance != account.balance || block.Link != bananoutils.ZeroBlockHash {
+			return "", "Balance and amount delta do not match"
+		}
+		account.frontier = hash
+		account.representative = block.Representative
+		account.blockCount++
+	default:
+		return "", "Invalid block subtype"
+	}
+	return hash, ""
+}
+
+func reply(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func randomHash() bananoutils.BlockHash {
+	hash := make([]byte, 32)
+	_, err := rand.Read(hash)
+	if err != nil {
+		panic(err)
+	}
+	return bananoutils.BlockHashFromBytes(hash)
+}
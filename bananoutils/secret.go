@@ -0,0 +1,35 @@
+package bananoutils
+
+import (
+	"fmt"
+	"io"
+)
+
+const redacted = "[REDACTED]"
+
+// Secret is a seed, private key or mnemonic. Printing it with fmt, logging it
+// or marshaling it as text gives a redacted placeholder so a careless log line
+// or panic cannot leak it. Reveal is the only way to get the value back.
+type Secret string
+
+// Reveal returns the secret value, only use it where the secret is meant to go.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return redacted
+}
+
+// Format redacts the secret for every verb, including %x and %q.
+func (s Secret) Format(f fmt.State, verb rune) {
+	io.WriteString(f, redacted)
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
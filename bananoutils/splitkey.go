@@ -0,0 +1,192 @@
+package bananoutils
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"filippo.io/edwards25519"
+	"github.com/bbedward/crypto/ed25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+// A split key search lets an untrusted machine look for a monKey without
+// learning its key. The owner gives the public key of an account they hold,
+// the searcher adds the public keys of random partial keys to it and only
+// sends back the partial key of a combined account it liked. The owner adds
+// their own secret scalar to the partial one to get the scalar of the
+// combined account.
+//
+// Wallets import seeds or private keys, which are hashed into the scalar, and
+// a hash cannot be run backwards. The combined account therefore only exists
+// as an ExpandedKey that can sign messages and blocks, it can never be
+// imported into a wallet.
+
+var (
+	ErrSplitKey         = errors.New("a split key is only a part of the key, combine it with the private key of its split base and sign with --expanded_key")
+	ErrInvalidPublicKey = errors.New("not a valid ed25519 public key")
+)
+
+// splitKeyPrefixDomain is hashed with the combined scalar to make the nonce
+// prefix of an ExpandedKey, the half of a normal private key digest that is
+// lost in a split key.
+const splitKeyPrefixDomain = "legion-van split key nonce:"
+
+// ExpandedKey is the scalar of an account without the private key it would
+// normally be hashed from, it is what combining a split key gives.
+type ExpandedKey struct {
+	scalar edwards25519.Scalar
+	prefix [KeySize]byte
+}
+
+func newExpandedKey(scalar *edwards25519.Scalar) *ExpandedKey {
+	key := &ExpandedKey{}
+	key.scalar.Set(scalar)
+	hash, err := blake2b.New256(nil)
+	if err != nil {
+		panic("Unable to create hash")
+	}
+	hash.Write([]byte(splitKeyPrefixDomain))
+	hash.Write(scalar.Bytes())
+	hash.Sum(key.prefix[:0])
+	return key
+}
+
+// ExpandedKeyFromHex reads a key printed by ExpandedKey.Hex.
+func ExpandedKeyFromHex(key string) (*ExpandedKey, error) {
+	bytes, err := hex.DecodeString(key)
+	if err != nil || len(bytes) != KeySize {
+		return nil, errors.New("expanded key must be 64 hex characters")
+	}
+	scalar, err := edwards25519.NewScalar().SetCanonicalBytes(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("not an expanded key: %w", err)
+	}
+	return newExpandedKey(scalar), nil
+}
+
+// Hex is the scalar of the key, it is a secret just like a private key.
+func (key *ExpandedKey) Hex() Secret {
+	return Secret(hex.EncodeToString(key.scalar.Bytes()))
+}
+
+func (key *ExpandedKey) PublicKey() ed25519.PublicKey {
+	return new(edwards25519.Point).ScalarBaseMult(&key.scalar).Bytes()
+}
+
+// Sign makes the same ed25519 signature with blake2b a private key of the
+// account would, with a different but still deterministic nonce.
+func (key *ExpandedKey) Sign(message []byte) []byte {
+	hash, err := blake2b.New512(nil)
+	if err != nil {
+		panic("Unable to create hash")
+	}
+	var digest [64]byte
+	hash.Write(key.prefix[:])
+	hash.Write(message)
+	hash.Sum(digest[:0])
+	r, _ := edwards25519.NewScalar().SetUniformBytes(digest[:])
+	R := new(edwards25519.Point).ScalarBaseMult(r).Bytes()
+
+	hash.Reset()
+	hash.Write(R)
+	hash.Write(key.PublicKey())
+	hash.Write(message)
+	hash.Sum(digest[:0])
+	k, _ := edwards25519.NewScalar().SetUniformBytes(digest[:])
+	s := edwards25519.NewScalar().MultiplyAdd(k, &key.scalar, r)
+
+	return append(R, s.Bytes()...)
+}
+
+// SignMessage is SignMessage for an expanded key.
+func (key *ExpandedKey) SignMessage(message string) Signature {
+	sig := hex.EncodeToString(key.Sign(messageHash(message)))
+	return Signature(strings.ToUpper(sig))
+}
+
+// secretScalar is the scalar a seed or private key signs with, the clamped
+// first half of the blake2b-512 of the private key.
+func secretScalar(keyType KeyType, secret string) (*edwards25519.Scalar, error) {
+	key, err := hex.DecodeString(secret)
+	if err != nil || len(key) != KeySize {
+		return nil, errors.New("secret must be 64 hex characters")
+	}
+	switch keyType {
+	case KeyTypeSeed:
+		// the private key of the first account, see KeypairFromSeed
+		hash := blake2b.Sum256(append(key, 0, 0, 0, 0))
+		key = hash[:]
+	case KeyTypeAdhoc:
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+	digest := blake2b.Sum512(key)
+	return edwards25519.NewScalar().SetBytesWithClamping(digest[:KeySize])
+}
+
+// partialScalar is the scalar of a partial split key, the partial key is
+// random so it is only clamped, not hashed.
+func partialScalar(partial []byte) (*edwards25519.Scalar, error) {
+	if len(partial) != KeySize {
+		return nil, errors.New("partial key must be 32 bytes")
+	}
+	return edwards25519.NewScalar().SetBytesWithClamping(partial)
+}
+
+// CheckPublicKey returns ErrInvalidPublicKey when pub is not a point on the
+// curve, so no account can have it.
+func CheckPublicKey(pub []byte) error {
+	_, err := new(edwards25519.Point).SetBytes(pub)
+	if err != nil {
+		return ErrInvalidPublicKey
+	}
+	return nil
+}
+
+// AddPublicKeys adds the points of two public keys, the result belongs to the
+// sum of their scalars.
+func AddPublicKeys(a, b []byte) (ed25519.PublicKey, error) {
+	pointA, err := new(edwards25519.Point).SetBytes(a)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	pointB, err := new(edwards25519.Point).SetBytes(b)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return new(edwards25519.Point).Add(pointA, pointB).Bytes(), nil
+}
+
+// SplitPublicKey is the public key of the account a partial key makes with
+// the split base public key.
+func SplitPublicKey(base ed25519.PublicKey, partial string) (ed25519.PublicKey, error) {
+	partialBytes, err := hex.DecodeString(partial)
+	if err != nil {
+		return nil, errors.New("partial key must be 64 hex characters")
+	}
+	scalar, err := partialScalar(partialBytes)
+	if err != nil {
+		return nil, err
+	}
+	return AddPublicKeys(base, new(edwards25519.Point).ScalarBaseMult(scalar).Bytes())
+}
+
+// CombineSplitKey adds the private key or seed of the split base account to a
+// partial key found for it and returns the key of the combined account.
+func CombineSplitKey(keyType KeyType, secret string, partial string) (*ExpandedKey, error) {
+	scalar, err := secretScalar(keyType, strings.ToLower(secret))
+	if err != nil {
+		return nil, err
+	}
+	partialBytes, err := hex.DecodeString(partial)
+	if err != nil {
+		return nil, errors.New("partial key must be 64 hex characters")
+	}
+	other, err := partialScalar(partialBytes)
+	if err != nil {
+		return nil, err
+	}
+	return newExpandedKey(scalar.Add(scalar, other)), nil
+}
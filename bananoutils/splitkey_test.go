@@ -0,0 +1,147 @@
+package bananoutils_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/bbedward/crypto/ed25519"
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+func TestSplitKey(t *testing.T) {
+	defer bananoutils.SetEntropySource(nil)
+	bananoutils.SetEntropySource(bananoutils.NewDeterministicEntropy([]byte("split")))
+
+	for _, keyType := range []bananoutils.KeyType{bananoutils.KeyTypeSeed, bananoutils.KeyTypeAdhoc} {
+		secret, baseAccount, err := bananoutils.GeneratePrivateKeyAndFirstPublicAddress()
+		if keyType == bananoutils.KeyTypeAdhoc {
+			secret, baseAccount, err = bananoutils.GenerateAdhocKeyAndPublicAddress()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		base, err := bananoutils.AddressToPub(baseAccount)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the searcher only knows base
+		deriver := bananoutils.NewKeyDeriver()
+		err = deriver.SetSplitBase(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		partials := make([]byte, 4*bananoutils.KeySize)
+		pubs := make([]byte, len(partials))
+		err = deriver.Generate(bananoutils.KeyTypeSplit, pubs, partials)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < len(partials); i += bananoutils.KeySize {
+			partial := hex.EncodeToString(partials[i : i+bananoutils.KeySize])
+			pub, err := bananoutils.SplitPublicKey(base, partial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(pub, pubs[i:i+bananoutils.KeySize]) {
+				t.Errorf("%s: deriver and SplitPublicKey disagree for partial %d", keyType, i/bananoutils.KeySize)
+			}
+
+			key, err := bananoutils.CombineSplitKey(keyType, secret, partial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(key.PublicKey(), pub) {
+				t.Fatalf("%s: combined key is for %s, expected %s", keyType, bananoutils.PubKeyToAddress(key.PublicKey()), bananoutils.PubKeyToAddress(pub))
+			}
+
+			message := []byte("monKey")
+			if !ed25519.Verify(pub, message, key.Sign(message)) {
+				t.Errorf("%s: signature of the combined key does not verify", keyType)
+			}
+			account := bananoutils.PubKeyToAddress(pub)
+			valid, err := bananoutils.VerifyMessage(account, "mine", key.SignMessage("mine"))
+			if err != nil || !valid {
+				t.Errorf("%s: message signature does not verify: %v", keyType, err)
+			}
+
+			again, err := bananoutils.ExpandedKeyFromHex(key.Hex().Reveal())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(again.Sign(message), key.Sign(message)) {
+				t.Errorf("%s: key read back from hex signs differently", keyType)
+			}
+		}
+	}
+}
+
+func TestSplitKeyErrors(t *testing.T) {
+	_, _, err := bananoutils.KeypairFromSecret(bananoutils.KeyTypeSplit, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	if !errors.Is(err, bananoutils.ErrSplitKey) {
+		t.Errorf("expected ErrSplitKey, got %v", err)
+	}
+
+	// y = 2 is not on the curve
+	notAPoint := make([]byte, bananoutils.KeySize)
+	notAPoint[0] = 2
+	if err := bananoutils.CheckPublicKey(notAPoint); !errors.Is(err, bananoutils.ErrInvalidPublicKey) {
+		t.Errorf("expected ErrInvalidPublicKey, got %v", err)
+	}
+	if err := bananoutils.NewKeyDeriver().PublicKeys(bananoutils.KeyTypeSplit, make([]byte, 32), make([]byte, 32)); err == nil {
+		t.Error("expected split keys without a base to fail")
+	}
+
+	// a scalar that is not reduced is not a key
+	_, err = bananoutils.ExpandedKeyFromHex("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	if err == nil {
+		t.Error("expected an unreduced scalar to be rejected")
+	}
+}
+
+func TestSplitKeySignsBlocks(t *testing.T) {
+	defer bananoutils.SetEntropySource(nil)
+	bananoutils.SetEntropySource(bananoutils.NewDeterministicEntropy([]byte("split block")))
+
+	secret, baseAccount, err := bananoutils.GeneratePrivateKeyAndFirstPublicAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := bananoutils.AddressToPub(baseAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := "0101010101010101010101010101010101010101010101010101010101010101"
+	pub, err := bananoutils.SplitPublicKey(base, partial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := bananoutils.CombineSplitKey(bananoutils.KeyTypeSeed, secret, partial)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := bananoutils.NewOpenBlock(bananoutils.PubKeyToAddress(pub), testRepresentative, "1", testSendHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = block.SignExpanded(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := block.VerifySignature()
+	if err != nil || !valid {
+		t.Errorf("expected the block signed by the combined key to verify: %v", err)
+	}
+
+	other, err := bananoutils.NewOpenBlock(baseAccount, testRepresentative, "1", testSendHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.SignExpanded(key); err == nil {
+		t.Error("expected the combined key to refuse a block of another account")
+	}
+}
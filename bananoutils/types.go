@@ -12,6 +12,23 @@ type Account string
 type Work string
 type Signature string
 
+// KeyType identifies how the secret saved for a found account has to be
+// imported into a wallet.
+type KeyType string
+
+const (
+	// KeyTypeSeed is a wallet seed, the account is derived at index 0.
+	KeyTypeSeed KeyType = "seed"
+	// KeyTypeAdhoc is a raw ed25519 private key for a single ad-hoc account.
+	KeyTypeAdhoc KeyType = "adhoc"
+	// KeyTypeNone is an account checked from its address alone, there is no
+	// secret for it.
+	KeyTypeNone KeyType = "none"
+	// KeyTypeSplit is a partial key of a split key search, it only gives the
+	// account after CombineSplitKey with the key of its split base.
+	KeyTypeSplit KeyType = "split"
+)
+
 func (hash BlockHash) ToBytes() []byte {
 	bytes, err := hex.DecodeString(string(hash))
 	if err != nil {
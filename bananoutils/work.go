@@ -0,0 +1,162 @@
+package bananoutils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// DefaultWorkThreshold is the Banano network difficulty for every block type.
+const DefaultWorkThreshold uint64 = 0xfffffe0000000000
+
+// how many nonces a worker tries between checking for cancellation.
+const workCheckInterval = 1 << 14
+
+// ParseWorkThreshold parses a difficulty threshold given as 16 hex characters.
+func ParseWorkThreshold(threshold string) (uint64, error) {
+	value, err := strconv.ParseUint(threshold, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("threshold must be 16 hex characters: %w", err)
+	}
+	return value, nil
+}
+
+// WorkValue calculates the difficulty a work nonce reaches for root, the block
+// is valid when the value is at least the network threshold.
+func WorkValue(root []byte, work Work) (uint64, error) {
+	nonce, err := strconv.ParseUint(string(work), 16, 64)
+	if err != nil || len(work) != 16 {
+		return 0, fmt.Errorf("work must be 16 hex characters: %q", work)
+	}
+	if len(root) != 32 {
+		return 0, fmt.Errorf("work root must be 32 bytes, got %d", len(root))
+	}
+	hasher, err := blake2b.New(8, nil)
+	if err != nil {
+		panic("Unable to create hash")
+	}
+	return workValue(hasher, root, nonce, make([]byte, 8), make([]byte, 0, 8)), nil
+}
+
+// ValidateWork reports whether work reaches threshold for root.
+func ValidateWork(root []byte, work Work, threshold uint64) (bool, error) {
+	value, err := WorkValue(root, work)
+	if err != nil {
+		return false, err
+	}
+	return value >= threshold, nil
+}
+
+// GenerateWork searches for a work nonce for root that reaches threshold using
+// threads goroutines, all cpus when threads is less than one. It returns the
+// context error if ctx is done first.
+func GenerateWork(ctx context.Context, root []byte, threshold uint64, threads int) (Work, error) {
+	if len(root) != 32 {
+		return "", fmt.Errorf("work root must be 32 bytes, got %d", len(root))
+	}
+	if threads < 1 {
+		threads = runtime.NumCPU()
+	}
+	var start [8]byte
+	_, err := rand.Read(start[:])
+	if err != nil {
+		return "", fmt.Errorf("could not get a random starting nonce: %w", err)
+	}
+	// Every worker starts in its own part of the nonce space.
+	first := binary.LittleEndian.Uint64(start[:])
+	stride := ^uint64(0) / uint64(threads)
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	found := make(chan uint64, threads)
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(nonce uint64) {
+			defer wg.Done()
+			hasher, err := blake2b.New(8, nil)
+			if err != nil {
+				panic("Unable to create hash")
+			}
+			nonceBytes := make([]byte, 8)
+			sum := make([]byte, 0, 8)
+			for {
+				for i := 0; i < workCheckInterval; i++ {
+					if workValue(hasher, root, nonce, nonceBytes, sum) >= threshold {
+						found <- nonce
+						return
+					}
+					nonce++
+				}
+				select {
+				case <-workCtx.Done():
+					return
+				default:
+				}
+			}
+		}(first + uint64(i)*stride)
+	}
+
+	select {
+	case nonce := <-found:
+		cancel()
+		wg.Wait()
+		return Work(fmt.Sprintf("%016x", nonce)), nil
+	case <-ctx.Done():
+		wg.Wait()
+		return "", ctx.Err()
+	}
+}
+
+// workValue hashes the little endian nonce with root, reusing the given hash
+// and buffers so workers do not allocate per attempt.
+func workValue(hasher hash.Hash, root []byte, nonce uint64, nonceBytes []byte, sum []byte) uint64 {
+	hasher.Reset()
+	binary.LittleEndian.PutUint64(nonceBytes, nonce)
+	hasher.Write(nonceBytes)
+	hasher.Write(root)
+	return binary.LittleEndian.Uint64(hasher.Sum(sum[:0]))
+}
+
+// WorkRoot is what the work of the block is calculated for, the previous
+// block or for the first block of an account its public key.
+func (block *StateBlock) WorkRoot() ([]byte, error) {
+	if block.Previous == ZeroBlockHash {
+		account, err := AddressToPub(block.Account)
+		if err != nil {
+			return nil, fmt.Errorf("bad account: %w", err)
+		}
+		return account, nil
+	}
+	return hashBytes("previous", block.Previous)
+}
+
+// GenerateWork calculates and sets the work of the block.
+func (block *StateBlock) GenerateWork(ctx context.Context, threshold uint64, threads int) error {
+	root, err := block.WorkRoot()
+	if err != nil {
+		return err
+	}
+	work, err := GenerateWork(ctx, root, threshold, threads)
+	if err != nil {
+		return err
+	}
+	block.Work = work
+	return nil
+}
+
+// ValidateWork reports whether the work of the block reaches threshold.
+func (block *StateBlock) ValidateWork(threshold uint64) (bool, error) {
+	root, err := block.WorkRoot()
+	if err != nil {
+		return false, err
+	}
+	return ValidateWork(root, block.Work, threshold)
+}
@@ -0,0 +1,77 @@
+package bananoutils_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// low enough to find in a few hundred attempts
+const testWorkThreshold uint64 = 0xff00000000000000
+
+func TestWorkValue(t *testing.T) {
+	// calculated independently with python's hashlib.blake2b
+	value, err := bananoutils.WorkValue(testSendHash.ToBytes(), "2feaeaa000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 0xdd78fff8ca6d5d88 {
+		t.Errorf("expected %016x to be equal to dd78fff8ca6d5d88", value)
+	}
+	_, err = bananoutils.WorkValue(testSendHash.ToBytes(), "beef")
+	if err == nil {
+		t.Error("expected an error for short work")
+	}
+}
+
+func TestGenerateWork(t *testing.T) {
+	root := testSendHash.ToBytes()
+	work, err := bananoutils.GenerateWork(context.Background(), root, testWorkThreshold, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := bananoutils.ValidateWork(root, work, testWorkThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Errorf("expected work %s to reach the threshold", work)
+	}
+}
+
+func TestGenerateWorkCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := bananoutils.GenerateWork(ctx, testSendHash.ToBytes(), ^uint64(0), 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled error, got %v", err)
+	}
+}
+
+func TestOpenBlockWork(t *testing.T) {
+	account, pub, _ := testSeedKeypair(t)
+	block, err := bananoutils.NewOpenBlock(account, testRepresentative, "1", testSendHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := block.WorkRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(root) != string(pub) {
+		t.Error("expected the work root of an open block to be the account public key")
+	}
+	err = block.GenerateWork(context.Background(), testWorkThreshold, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := block.ValidateWork(testWorkThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Errorf("expected work %s to reach the threshold", block.Work)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+type blockCommand struct{}
+
+type blockWorkOptions struct {
+	WorkThreshold string `long:"work_threshold" description:"Difficulty the work has to reach as 16 hex characters." default:"fffffe0000000000"`
+	WorkThreads   int    `long:"work_threads" description:"Threads used to calculate work, 0 uses all cpus." default:"0"`
+	NoWork        bool   `long:"no_work" description:"Only sign the block and leave calculating work to the node."`
+}
+
+type openBlockCommand struct {
+	Representative string           `long:"representative" required:"yes" description:"Representative of the new account."`
+	Balance        string           `long:"balance" required:"yes" description:"Balance in raw after receiving the send block."`
+	SendHash       string           `long:"send_hash" required:"yes" description:"Hash of the pending send block to the monKey address."`
+	ExpandedKey    bool             `long:"expanded_key" description:"Sign with the expanded key combine printed for a split key monKey, it is asked for or read from LEGION_VAN_EXPANDED_KEY."`
+	Work           blockWorkOptions `group:"Work Options"`
+	Args           struct {
+		MonkeyFile string `positional-arg-name:"monkey.json" required:"yes" description:"Found monKey json file to sign with."`
+	} `positional-args:"yes"`
+}
+
+type changeBlockCommand struct {
+	Representative string           `long:"representative" required:"yes" description:"New representative of the account."`
+	Balance        string           `long:"balance" required:"yes" description:"Current balance of the account in raw."`
+	Previous       string           `long:"previous" required:"yes" description:"Hash of the current frontier block of the account."`
+	ExpandedKey    bool             `long:"expanded_key" description:"Sign with the expanded key combine printed for a split key monKey, it is asked for or read from LEGION_VAN_EXPANDED_KEY."`
+	Work           blockWorkOptions `group:"Work Options"`
+	Args           struct {
+		MonkeyFile string `positional-arg-name:"monkey.json" required:"yes" description:"Found monKey json file to sign with."`
+	} `positional-args:"yes"`
+}
+
+var (
+	blockCmd       blockCommand
+	openBlockCmd   openBlockCommand
+	changeBlockCmd changeBlockCommand
+)
+
+func (cmd *openBlockCommand) Execute(args []string) error {
+	key, err := loadSigningKey(cmd.Args.MonkeyFile, cmd.ExpandedKey)
+	if err != nil {
+		return err
+	}
+	block, err := bananoutils.NewOpenBlock(bananoutils.Account(key.address), bananoutils.Account(cmd.Representative), cmd.Balance, bananoutils.BlockHash(cmd.SendHash))
+	if err != nil {
+		return err
+	}
+	return cmd.Work.finish(block, key)
+}
+
+func (cmd *changeBlockCommand) Execute(args []string) error {
+	key, err := loadSigningKey(cmd.Args.MonkeyFile, cmd.ExpandedKey)
+	if err != nil {
+		return err
+	}
+	block, err := bananoutils.NewChangeBlock(bananoutils.Account(key.address), bananoutils.BlockHash(cmd.Previous), bananoutils.Account(cmd.Representative), cmd.Balance)
+	if err != nil {
+		return err
+	}
+	return cmd.Work.finish(block, key)
+}
+
+// finish signs the block, calculates its work and prints it as json.
+func (options blockWorkOptions) finish(block *bananoutils.StateBlock, key signingKey) error {
+	err := key.signBlock(block)
+	if err != nil {
+		return err
+	}
+	if !options.NoWork {
+		threshold, err := bananoutils.ParseWorkThreshold(options.WorkThreshold)
+		if err != nil {
+			return err
+		}
+		err = block.GenerateWork(context.Background(), threshold, options.WorkThreads)
+		if err != nil {
+			return fmt.Errorf("could not calculate work: %w", err)
+		}
+	}
+	data, err := json.MarshalIndent(block, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
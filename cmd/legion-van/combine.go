@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bbedward/crypto/ed25519"
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+// splitSecretEnv lets headless runs supply the key of the split base without
+// a prompt.
+const splitSecretEnv = "LEGION_VAN_SPLIT_SECRET"
+
+// expandedKeyEnv lets headless runs supply the expanded key printed by combine
+// without a prompt.
+const expandedKeyEnv = "LEGION_VAN_EXPANDED_KEY"
+
+type combineCommand struct {
+	KeyType string `long:"key_type" description:"How the key of the --split_key address is imported into your wallet." default:"seed" choice:"seed" choice:"adhoc"`
+	Message string `long:"message" description:"Also sign this message with the combined key, anyone can check it with proof verify."`
+	Args    struct {
+		MonkeyFile string `positional-arg-name:"monkey.json" required:"yes" description:"monKey json file found with --split_key."`
+	} `positional-args:"yes"`
+}
+
+var combineCmd combineCommand
+
+func (cmd *combineCommand) Execute(args []string) error {
+	monkey, err := loadUnlockedMonkey(cmd.Args.MonkeyFile)
+	if err != nil {
+		return err
+	}
+	if monkey.KeyType != bananoutils.KeyTypeSplit || monkey.SplitBase == "" {
+		return fmt.Errorf("%s was not found with --split_key", cmd.Args.MonkeyFile)
+	}
+
+	secret, ok := os.LookupEnv(splitSecretEnv)
+	if !ok {
+		line, err := promptPassphrase(fmt.Sprintf("%s of %s: ", strings.Title(cmd.KeyType), monkey.SplitBase))
+		if err != nil {
+			return err
+		}
+		secret = string(line)
+	}
+	secret = strings.ToLower(strings.TrimSpace(secret))
+	keyType := bananoutils.KeyType(cmd.KeyType)
+	pub, _, err := bananoutils.KeypairFromSecret(keyType, secret)
+	if err != nil {
+		return err
+	}
+	if account := bananoutils.PubKeyToAddress(pub); string(account) != monkey.SplitBase {
+		return fmt.Errorf("the %s is for %s, not the split base %s", keyType, account, monkey.SplitBase)
+	}
+
+	key, err := bananoutils.CombineSplitKey(keyType, secret, monkey.PrivateKey.Reveal())
+	if err != nil {
+		return err
+	}
+	if account := bananoutils.PubKeyToAddress(key.PublicKey()); string(account) != monkey.PublicAddress {
+		return fmt.Errorf("combined key is for %s not %s, the monKey file does not belong to this split base", account, monkey.PublicAddress)
+	}
+	fmt.Printf("address:      %s\nexpanded_key: %s\n", monkey.PublicAddress, key.Hex().Reveal())
+	fmt.Fprintln(os.Stderr, "The expanded key is the account scalar, not a seed or private key. Wallets cannot import it, keep it safe and sign blocks and proofs with it here using --expanded_key.")
+	if cmd.Message != "" {
+		fmt.Printf("message:      %s\nsignature:    %s\n", cmd.Message, key.SignMessage(cmd.Message))
+	}
+	return nil
+}
+
+// signingKey is the key of a found monKey, the private key of a seed or adhoc
+// monKey or the expanded key of a combined split key monKey.
+type signingKey struct {
+	address  string
+	priv     ed25519.PrivateKey
+	expanded *bananoutils.ExpandedKey
+}
+
+// loadSigningKey reads the key of the monKey in fileName. With expanded the
+// monKey has to be a split key one and its expanded key is asked for instead.
+func loadSigningKey(fileName string, expanded bool) (signingKey, error) {
+	if !expanded {
+		monkey, err := loadUnlockedMonkey(fileName)
+		if err != nil {
+			return signingKey{}, err
+		}
+		_, priv, err := monkey.Keypair()
+		return signingKey{address: monkey.PublicAddress, priv: priv}, err
+	}
+	// the partial key is not needed, so neither is the passphrase
+	monkey, err := engine.LoadMonkeyFile(fileName)
+	if err != nil {
+		return signingKey{}, err
+	}
+	if monkey.KeyType != bananoutils.KeyTypeSplit {
+		return signingKey{}, fmt.Errorf("%s was not found with --split_key, it has no expanded key", fileName)
+	}
+	line, ok := os.LookupEnv(expandedKeyEnv)
+	if !ok {
+		answer, err := promptPassphrase(fmt.Sprintf("Expanded key of %s: ", monkey.PublicAddress))
+		if err != nil {
+			return signingKey{}, err
+		}
+		line = string(answer)
+	}
+	key, err := bananoutils.ExpandedKeyFromHex(strings.ToLower(strings.TrimSpace(line)))
+	if err != nil {
+		return signingKey{}, err
+	}
+	if account := bananoutils.PubKeyToAddress(key.PublicKey()); string(account) != monkey.PublicAddress {
+		return signingKey{}, fmt.Errorf("the expanded key is for %s, not %s", account, monkey.PublicAddress)
+	}
+	return signingKey{address: monkey.PublicAddress, expanded: key}, nil
+}
+
+func (key signingKey) signBlock(block *bananoutils.StateBlock) error {
+	if key.expanded != nil {
+		return block.SignExpanded(key.expanded)
+	}
+	return block.Sign(key.priv)
+}
+
+func (key signingKey) signMessage(message string) bananoutils.Signature {
+	if key.expanded != nil {
+		return key.expanded.SignMessage(message)
+	}
+	return bananoutils.SignMessage(key.priv, message)
+}
+
+// parsePublicKey reads an address or a 64 character hex public key.
+func parsePublicKey(key string) (ed25519.PublicKey, error) {
+	if _, err := bananoutils.AddressPrefix(bananoutils.Account(key)); err == nil {
+		return bananoutils.AddressToPub(bananoutils.Account(key))
+	}
+	pub, err := hex.DecodeString(key)
+	if err != nil || len(pub) != bananoutils.KeySize {
+		return nil, errors.New("expected an address or a 64 character hex public key")
+	}
+	return pub, nil
+}
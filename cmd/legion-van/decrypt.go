@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+type decryptCommand struct {
+	Args struct {
+		MonkeyFile string `positional-arg-name:"monkey.json" required:"yes" description:"Found monKey json file saved with --encrypt."`
+	} `positional-args:"yes"`
+}
+
+var decryptCmd decryptCommand
+
+func (cmd *decryptCommand) Execute(args []string) error {
+	monkey, err := engine.LoadMonkeyFile(cmd.Args.MonkeyFile)
+	if err != nil {
+		return err
+	}
+	if monkey.EncryptedSecret == nil {
+		return fmt.Errorf("%s is not encrypted", cmd.Args.MonkeyFile)
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	privateKey, mnemonic, err := engine.DecryptSecret(monkey, passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("address:     %s\nkey_type:    %s\nprivate_key: %s\n", monkey.PublicAddress, monkey.KeyType, privateKey.Reveal())
+	if mnemonic != "" {
+		fmt.Printf("mnemonic:    %s\n", mnemonic.Reveal())
+	}
+	return nil
+}
+
+// loadUnlockedMonkey loads a monKey json file and decrypts its secret first
+// if it was saved with --encrypt.
+func loadUnlockedMonkey(fileName string) (engine.MonkeyStats, error) {
+	monkey, err := engine.LoadMonkeyFile(fileName)
+	if err != nil || monkey.EncryptedSecret == nil {
+		return monkey, err
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return monkey, err
+	}
+	monkey.PrivateKey, monkey.Mnemonic, err = engine.DecryptSecret(monkey, passphrase)
+	if err != nil {
+		return monkey, err
+	}
+	monkey.EncryptedSecret = nil
+	return monkey, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+	legionImage "github.com/steampoweredtaco/legion-van/image"
+)
+
+type inspectCommand struct {
+	Width   int  `long:"width" description:"Width of the monKey picture in terminal columns." default:"40"`
+	NoImage bool `long:"no_image" description:"Only print the traits, do not draw the monKey."`
+	Args    struct {
+		Value string `positional-arg-name:"seed|mnemonic|private_key|address" required:"yes" description:"What to inspect, - reads it from stdin so secrets stay out of the shell history."`
+	} `positional-args:"yes"`
+}
+
+var inspectCmd inspectCommand
+
+// inspectTarget is an account to inspect and where it came from.
+type inspectTarget struct {
+	address bananoutils.Account
+	source  string
+}
+
+func (cmd *inspectCommand) Execute(args []string) error {
+	value := cmd.Args.Value
+	if value == "-" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		value = line
+	}
+	targets, err := inspectTargets(strings.TrimSpace(value))
+	if err != nil {
+		return err
+	}
+
+	bananoutils.ChangeMonkeyServer(config.MonkeyServer)
+	if !cmd.NoImage {
+		legionImage.Init()
+		defer legionImage.Destroy()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, target := range targets {
+		err = cmd.inspect(ctx, target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inspectTargets works out the accounts value stands for. A hex key could be
+// a wallet seed or an ad-hoc private key so both accounts are inspected.
+func inspectTargets(value string) ([]inspectTarget, error) {
+	if _, err := bananoutils.AddressPrefix(bananoutils.Account(value)); err == nil {
+		_, err = bananoutils.AddressToPub(bananoutils.Account(value))
+		if err != nil {
+			return nil, err
+		}
+		return []inspectTarget{{address: bananoutils.Account(value), source: "address"}}, nil
+	}
+	if strings.Contains(value, " ") {
+		seed, err := bananoutils.MnemonicToSeed(value)
+		if err != nil {
+			return nil, err
+		}
+		value = hex.EncodeToString(seed)
+		pub, _, err := bananoutils.KeypairFromSecret(bananoutils.KeyTypeSeed, value)
+		if err != nil {
+			return nil, err
+		}
+		return []inspectTarget{{address: bananoutils.PubKeyToAddress(pub), source: "mnemonic seed"}}, nil
+	}
+	key, err := hex.DecodeString(value)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("not an address, mnemonic or 64 hex character seed or private key")
+	}
+	var targets []inspectTarget
+	for _, keyType := range []bananoutils.KeyType{bananoutils.KeyTypeSeed, bananoutils.KeyTypeAdhoc} {
+		pub, _, err := bananoutils.KeypairFromSecret(keyType, value)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, inspectTarget{address: bananoutils.PubKeyToAddress(pub), source: "as " + string(keyType)})
+	}
+	return targets, nil
+}
+
+func (cmd *inspectCommand) inspect(ctx context.Context, target inspectTarget) error {
+	monkey, err := engine.FetchMonkeyStats(ctx, string(target.address))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("address: %s (%s)\n", target.address, target.source)
+	if !cmd.NoImage {
+		err = printMonkey(ctx, target.address, cmd.Width)
+		if err != nil {
+			return err
+		}
+	}
+
+	traits, oneIn := engine.MonkeyOdds(monkey)
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, trait := range traits {
+		odds := "unknown"
+		if trait.Odds > 0 {
+			odds = oneInString(1 / trait.Odds)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", trait.Category, trait.Trait, odds)
+	}
+	fmt.Fprintf(table, "all traits\t\t%s\n\n", oneInString(oneIn))
+	return table.Flush()
+}
+
+// oneInString keeps a decimal for common traits, 1 in 1 would be misleading.
+func oneInString(oneIn float64) string {
+	if oneIn < 100 {
+		return fmt.Sprintf("1 in %.1f", oneIn)
+	}
+	return fmt.Sprintf("1 in %.0f", oneIn)
+}
+
+func printMonkey(ctx context.Context, address bananoutils.Account, width int) error {
+	monkeySVG, err := bananoutils.GrabMonkey(ctx, address, legionImage.SVGFormat)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(monkeySVG)
+	if err != nil {
+		return err
+	}
+	imagePNG, err := legionImage.ConvertSvgToBinary(data, legionImage.PNGFormat, 250)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(bytes.NewReader(imagePNG))
+	if err != nil {
+		return fmt.Errorf("could not decode monkey image: %w", err)
+	}
+	return legionImage.PrintImage(os.Stdout, img, width)
+}
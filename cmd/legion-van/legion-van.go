@@ -7,11 +7,13 @@ import (
 	mrand "math/rand"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	_ "net/http/pprof"
@@ -20,9 +22,11 @@ import (
 	"github.com/jessevdk/go-flags"
 	log "github.com/sirupsen/logrus"
 	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/bananoutils/rpc"
 	"github.com/steampoweredtaco/legion-van/engine"
 	"github.com/steampoweredtaco/legion-van/gui"
 	legionImage "github.com/steampoweredtaco/legion-van/image"
+	"github.com/steampoweredtaco/legion-van/index"
 )
 
 func (t *targetFormat) String() string {
@@ -47,10 +51,34 @@ var config struct {
 	MonkeyServer   string        `long:"monkey_api" description:"To change the backend monkey server, defaults to the official one." default:"https://monkey.banano.cc"`
 	NumOfThreads   int           `long:"threads" description:"Changes number of threads to use, defaults to 2, with a decent machine this is probably all you need. Set to -1 for all hardware cpu threads available." default:"2"`
 	NoGui          bool          `long:"nogui" short:"g" description:"Do not use a terminal gui just give you the straight banano."`
+	Mnemonic       bool          `long:"mnemonic" description:"Also save the 24 word BIP39 mnemonic of found wallet seeds."`
+	Encrypt        bool          `long:"encrypt" description:"Encrypt the private key and mnemonic of found monKeys with a passphrase, read from LEGION_VAN_PASSPHRASE or prompted for. Use the decrypt command to reveal them."`
+	NodeRPC        string        `long:"node_rpc" description:"Banano node RPC url used by the publish command and --check_unopened, use a node you control."`
+	CheckUnopened  bool          `long:"check_unopened" description:"Ask the --node_rpc node that every found monKey address is still unopened before saving it."`
+	Input          string        `long:"input" description:"Test the wallets in this file instead of random ones, - reads stdin. Lines are addresses, hex seeds or private keys, mnemonics or json objects. The run ends when the input is done or --duration is up."`
+	VanityInput    string        `long:"vanity_input" description:"Test the accounts printed by a text vanity generator like nano-vanity instead of random ones, - reads stdin. Every address is checked against its key."`
+	Adhoc          bool          `long:"adhoc" description:"Search with ad-hoc private keys instead of wallet seeds. Saved keys are marked with key_type adhoc and must be imported as a private key, not a seed."`
+	Output         []string      `long:"output" description:"Where found monKeys are saved, repeat it to save to more than one. dir writes files to foundMonKeys, jsonl prints a json line to stdout and needs --nogui, archive adds them to a single tar file per run in foundMonKeys." default:"dir" choice:"dir" choice:"jsonl" choice:"archive"`
+	Webhook        string        `long:"webhook" description:"Post json events to this url when the session starts and finishes, a monKey is found, without its secret, or an error is logged. Failed posts are retried."`
+	WebhookSecret  string        `long:"webhook_secret" env:"LEGION_VAN_WEBHOOK_SECRET" description:"Sign --webhook posts with this secret, the X-Legion-Van-Signature header is sha256= and the hex HMAC-SHA256 of the body."`
+	SplitKey       string        `long:"split_key" description:"Search for partial keys to add to this address or hex public key, so a machine you do not trust never has the final key. Use the combine command with the key of this address to use a found monKey, the combined key cannot be imported into wallets."`
+
+	// Commands run for every found monKey.
+	OnFound            string        `long:"on-found" description:"Run this shell command for every found monKey with its json on stdin and LEGION_VAN_IMAGE, LEGION_VAN_ADDRESS, LEGION_VAN_SILLY_NAME, LEGION_VAN_KEY_TYPE and LEGION_VAN_SESSION set. The json has the secret unless --encrypt or --on-found-no-secret is used. A failing command is logged, the search goes on."`
+	OnFoundNoSecret    bool          `long:"on-found-no-secret" description:"Leave the seed, private key and mnemonic out of the json given to --on-found."`
+	OnFoundTimeout     time.Duration `long:"on-found-timeout" description:"How long --on-found may run for a single monKey before it is killed." default:"1m"`
+	OnFoundConcurrency int           `long:"on-found-concurrency" description:"How many --on-found commands may run at once, more wait their turn." default:"2"`
+
+	// Reproducible keys for testing, never to be used for real monKeys.
+	InsecureDeterministicSeed string `long:"insecure-deterministic-seed" hidden:"yes"`
 }
 
 var odds = 0.0
 
+// foundDirName is where found monKeys are saved, in the directory the search
+// runs in.
+const foundDirName = "foundMonKeys"
+
 var filter engine.CmdLineFilter
 
 func printVanityFilterUsage() {
@@ -160,30 +188,124 @@ func makeLower(as []string) []string {
 	return res
 }
 
+// normalizeFilter cleans up the parsed vanity filters.
+func normalizeFilter() {
+	filter.Hat = makeLower(filter.Hat)
+	filter.Glasses = makeLower(filter.Glasses)
+	filter.Mouth = makeLower(filter.Mouth)
+	filter.Cloths = makeLower(filter.Cloths)
+	filter.Feet = makeLower(filter.Feet)
+	filter.Tail = makeLower(filter.Tail)
+	filter.Misc = makeLower(filter.Misc)
+
+	engine.SimplifyFilters(&filter)
+}
+
 func parseFlags() {
 	parser := flags.NewParser(&config, flags.Default)
 	parser.AddGroup("Vanity Filters", "These options allow for filtering of specific monKey features.", &filter)
+	parser.SubcommandsOptional = true
+	parser.AddCommand("mnemonic", "Convert a found monKey seed to and from BIP39 words",
+		"Prints the 24 BIP39 words for the seed saved in a monKey json file, or with --words checks the words and prints the seed and address they belong to.",
+		&mnemonicCmd)
+	parser.AddCommand("decrypt", "Reveal the secret of a monKey saved with --encrypt",
+		"Prompts for the passphrase, or reads it from "+passphraseEnv+", and prints the private key and mnemonic of an encrypted monKey json file.",
+		&decryptCmd)
+	blockCommand, _ := parser.AddCommand("block", "Prepare signed blocks for a found monKey offline",
+		"Builds, signs and calculates work for state blocks of a found monKey account, the block json is printed ready for the node process RPC.",
+		&blockCmd)
+	blockCommand.AddCommand("open", "Prepare the open block receiving a pending send",
+		"Prepares the first block of the account, receiving the send block --send_hash.",
+		&openBlockCmd)
+	blockCommand.AddCommand("change", "Prepare a representative change block",
+		"Prepares a block changing the representative of an already opened account.",
+		&changeBlockCmd)
+	parser.AddCommand("inspect", "Show the monKey of a seed, private key or address",
+		"Derives the account of a hex seed, mnemonic or private key, or takes an address, then draws its monKey and prints the odds of its traits.",
+		&inspectCmd)
+	parser.AddCommand("publish", "Publish a prepared block to a node",
+		"Sends a block json prepared by the block command to the --node_rpc node, open and change blocks are supported.",
+		&publishCmd)
+	proofCommand, _ := parser.AddCommand("proof", "Prove you own a found monKey without sharing its key",
+		"Signs a message with the key of a found monKey, anyone can check the signature against the monKey address.",
+		&proofCmd)
+	proofCommand.AddCommand("sign", "Sign a message with a found monKey",
+		"Prints a signature of --message made with the key of the monKey json file.",
+		&proofSignCmd)
+	proofCommand.AddCommand("verify", "Check a signed message",
+		"Checks --signature was made by the key of --address for --message.",
+		&proofVerifyCmd)
+	parser.AddCommand("combine", "Combine a split key monKey with your key",
+		"Adds the private key or seed of the --split_key address to the partial key of a monKey found with --split_key, and prints the expanded key of the monKey account. Wallets cannot import it, it can only sign here.",
+		&combineCmd)
+	parser.AddCommand("query", "List found monKeys from the index",
+		"Looks up found monKeys in the index of foundMonKeys by the vanity filters, like -H crown -O cigar, the address filters and --session, rarest first. Queries can run while a search is going.",
+		&queryCmd)
+	parser.AddCommand("verify", "Check the monKeys saved in foundMonKeys",
+		"Derives the address of every saved monKey from its key, asks the monkey server for its traits and image again, finds json files without images and images without json files, and checks the sha256 manifest of each session for changed files.",
+		&verifyCmd)
+	parser.AddCommand("migrate", "Upgrade saved monKey json files to the current schema",
+		"Rewrites the json files in foundMonKeys saved by older versions as schema version 2, described by schema/monkey-v2.schema.json, and updates the sha256 manifests listing them. Don't run it while a search is saving to foundMonKeys.",
+		&migrateCmd)
 	_, err := parser.Parse()
 
 	if err != nil {
 		os.Exit(1)
 	}
 
+	// commands run while parsing, there is no search to start afterwards
+	if parser.Active != nil {
+		os.Exit(0)
+	}
+
 	if filter.HelpVanity {
 		printVanityFilterUsage()
 		os.Exit(1)
 	}
 
-	filter.Hat = makeLower(filter.Hat)
-	filter.Glasses = makeLower(filter.Glasses)
-	filter.Mouth = makeLower(filter.Mouth)
-	filter.Cloths = makeLower(filter.Cloths)
-	filter.Feet = makeLower(filter.Feet)
-	filter.Tail = makeLower(filter.Tail)
-	filter.Misc = makeLower(filter.Misc)
-
-	engine.SimplifyFilters(&filter)
+	normalizeFilter()
+	pattern, err := filter.AddressPattern()
+	if err != nil {
+		log.Fatal(err)
+	}
 	odds = engine.GetOdds(filter)
+	if _, known := pattern.OneIn(); !known {
+		log.Warn("The odds do not include --address_regex, it can make them a lot longer.")
+	}
+
+	if config.Adhoc {
+		engine.SetKeyType(bananoutils.KeyTypeAdhoc)
+	}
+	if config.SplitKey != "" {
+		if config.Adhoc || config.Input != "" || config.VanityInput != "" {
+			log.Fatal("--split_key searches its own partial keys, it cannot be used with --adhoc, --input or --vanity_input")
+		}
+		pub, err := parsePublicKey(config.SplitKey)
+		if err != nil {
+			log.Fatalf("bad --split_key: %s", err)
+		}
+		err = engine.SetSplitBase(pub)
+		if err != nil {
+			log.Fatalf("bad --split_key: %s", err)
+		}
+		log.Infof("Searching partial keys for %s, combine found monKeys with its key.", bananoutils.PubKeyToAddress(pub))
+	}
+	if config.Encrypt && (config.Input == "-" || config.VanityInput == "-") {
+		// the prompt would read the passphrase, and more, from the wallets
+		if _, ok := os.LookupEnv(passphraseEnv); !ok {
+			log.Fatalf("--encrypt cannot prompt for the passphrase while the wallets are read from stdin, set %s", passphraseEnv)
+		}
+	}
+
+	if config.InsecureDeterministicSeed != "" {
+		err = engine.SetEntropySource(bananoutils.NewDeterministicEntropy([]byte(config.InsecureDeterministicSeed)))
+		log.Warn("INSECURE deterministic keys are used, found monKeys will not be saved.")
+	} else {
+		err = bananoutils.CheckEntropy(bananoutils.EntropySource())
+	}
+	if err != nil {
+		log.Fatalf("the entropy source failed its health check, refusing to make keys: %s", err)
+	}
 }
 
 func setupHttp() {
@@ -206,7 +328,7 @@ func setupLog() io.Closer {
 
 	logFile, err := os.OpenFile("legion-van.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
 	if err != nil {
-		log.Fatal("could not open log file: %s", err)
+		log.Fatalf("could not open log file: %s", err)
 		os.Exit(-1)
 	}
 
@@ -221,6 +343,8 @@ func setupLog() io.Closer {
 	if config.VerboseLog {
 		log.SetLevel(log.DebugLevel)
 	}
+	// must be the first hook so the gui log view only gets scrubbed entries
+	log.AddHook(engine.RedactHook{})
 	return logFile
 }
 
@@ -240,13 +364,134 @@ func setupGui(ctx context.Context, cleanupMain context.CancelFunc) *gui.MainApp
 
 }
 
+// handleSignals ends the run on SIGINT or SIGTERM the same way ESC does,
+// saving every monKey already found. A second signal quits right away.
+func handleSignals(guiInstance *gui.MainApp) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Warnf("Got %s, saving the monKeys already found before quitting. Again to quit right away.", sig)
+		guiInstance.RequestQuit()
+		sig = <-signals
+		// out of raw mode first, or the shell is left unusable
+		guiInstance.Stop()
+		fmt.Fprintf(os.Stderr, "Got %s again, quitting without waiting for writes.\n", sig)
+		code := 1
+		if number, ok := sig.(syscall.Signal); ok {
+			code = 128 + int(number)
+		}
+		os.Exit(code)
+	}()
+}
+
+// setupInput starts reading the --input or --vanity_input wallets, nil when
+// searching random ones.
+func setupInput(ctx context.Context) <-chan engine.Wallet {
+	input, read := config.Input, engine.ReadWallets
+	if config.VanityInput != "" {
+		if input != "" {
+			log.Fatal("use either --input or --vanity_input, not both")
+		}
+		input, read = config.VanityInput, engine.ReadVanityWallets
+	}
+	if input == "" {
+		return nil
+	}
+	if input == "-" {
+		return read(ctx, os.Stdin)
+	}
+	file, err := os.Open(input)
+	if err != nil {
+		log.Fatalf("could not open input: %s", err)
+	}
+	return read(ctx, file)
+}
+
+func hasOutput(output string) bool {
+	for _, chosen := range config.Output {
+		if chosen == output {
+			return true
+		}
+	}
+	return false
+}
+
+// setupWebhook makes the --webhook notifier, nil when there is none.
+func setupWebhook() *engine.Webhook {
+	if config.Webhook == "" {
+		return nil
+	}
+	webhook := engine.NewWebhook(config.Webhook, config.WebhookSecret)
+	// after the redact hook from setupLog, errors are scrubbed before posting
+	log.AddHook(webhook)
+	return webhook
+}
+
+// setupSink opens every --output, the --on-found sink and webhook for
+// session.
+func setupSink(targetDir string, session string, webhook *engine.Webhook) engine.Sink {
+	var sinks []engine.Sink
+	for _, output := range config.Output {
+		switch output {
+		case "dir":
+			sinks = append(sinks, engine.NewDirectorySink(targetDir), engine.NewManifestSink(targetDir))
+		case "jsonl":
+			if !config.NoGui {
+				log.Fatal("--output jsonl prints to stdout, it needs --nogui")
+			}
+			sinks = append(sinks, engine.NewJSONLinesSink(os.Stdout))
+		case "archive":
+			sinks = append(sinks, engine.NewArchiveSink(targetDir))
+		}
+	}
+	if config.OnFound != "" {
+		options := engine.ExecOptions{
+			Timeout:     config.OnFoundTimeout,
+			Concurrency: config.OnFoundConcurrency,
+			NoSecret:    config.OnFoundNoSecret,
+		}
+		if hasOutput("dir") {
+			// the dir sink is before this one so the image is already saved
+			options.ImageDir = targetDir
+		}
+		sinks = append(sinks, engine.NewExecSink(config.OnFound, options))
+	}
+	sinks = append(sinks, index.NewSink(filepath.Join(targetDir, index.FileName), foundFiles(targetDir)))
+	if webhook != nil {
+		// last, so a receiver told about a monKey finds it saved already
+		sinks = append(sinks, webhook)
+	}
+	sink := engine.NewFanOut(sinks...)
+	err := sink.Open(session)
+	if err != nil {
+		log.Fatalf("could not open the outputs: %s", err)
+	}
+	return sink
+}
+
+// foundFiles says where the --output sinks save a found monKey, for the index.
+func foundFiles(targetDir string) func(session string, found engine.FoundMonkey) []string {
+	return func(session string, found engine.FoundMonkey) []string {
+		var files []string
+		if hasOutput("dir") {
+			target := path.Join(targetDir, found.FileName())
+			files = append(files, target+".json", target+found.ImageExtension)
+		}
+		if hasOutput("archive") {
+			files = append(files, path.Join(targetDir, engine.ArchiveName(session)))
+		}
+		return files
+	}
+}
+
 // setupOutputDir creates target output dir and returns the absolute path of the target directory.
 func setupOutputDir() string {
 	curdir, err := os.Getwd()
 	if err != nil {
 		log.Fatal("Can't get current directory.")
 	}
-	targetDir := path.Join(curdir, "foundMonKeys")
+	targetDir := path.Join(curdir, foundDirName)
 	targetDir, err = filepath.Abs(targetDir)
 	if err != nil {
 		log.Fatalf("could not resolve directory path: %s", err)
@@ -265,6 +510,32 @@ func setupOutputDir() string {
 	return targetDir
 }
 
+func setupOutputOptions(targetDir string) engine.OutputOptions {
+	options := engine.OutputOptions{
+		Mnemonic: config.Mnemonic,
+		Insecure: config.InsecureDeterministicSeed != "",
+		Pending:  engine.NewPendingQueue(targetDir),
+	}
+	if config.CheckUnopened {
+		if config.NodeRPC == "" {
+			log.Fatal("--check_unopened needs a node, set --node_rpc")
+		}
+		options.Node = rpc.NewClient(config.NodeRPC, nil)
+	}
+	if !config.Encrypt {
+		return options
+	}
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		log.Fatalf("could not read passphrase: %s", err)
+	}
+	options.Encrypter, err = engine.NewSecretEncrypter(passphrase)
+	if err != nil {
+		log.Fatalf("could not setup encryption: %s", err)
+	}
+	return options
+}
+
 func main() {
 	//runtime.SetBlockProfileRate(1)
 	parseFlags()
@@ -277,20 +548,47 @@ func main() {
 	log.Infof("Using %d cpus", runtime.GOMAXPROCS(config.NumOfThreads))
 
 	targetDir := setupOutputDir()
+	session := engine.NewSessionID()
+	outputOptions := setupOutputOptions(targetDir)
+	outputOptions.Session = session
+	log.Infof("Starting session %s", session)
+	webhook := setupWebhook()
+	sink := setupSink(targetDir, session, webhook)
 	backgroundCtx := context.Background()
 	guiCtx, guiCancel := context.WithCancel(backgroundCtx)
+	// previews stop before the gui, once every found monKey is saved
+	previewCtx, previewCancel := context.WithCancel(guiCtx)
 	mainCtx, mainCancel := context.WithTimeout(backgroundCtx, config.HowLongToRun)
 	guiInstance := setupGui(guiCtx, mainCancel)
+	handleSignals(guiInstance)
+	if hasOutput("jsonl") {
+		// stdout is only for the monKey json lines
+		guiInstance.SetPipeOutput(os.Stderr)
+	}
+	inputWallets := setupInput(mainCtx)
 
 	var writeWG sync.WaitGroup
+	if !outputOptions.Insecure {
+		// monKeys an earlier run found but could not get images for
+		writeWG.Add(1)
+		go func() {
+			defer writeWG.Done()
+			err := engine.FinishPending(outputOptions.Pending, sink, config.Format.String())
+			if err != nil {
+				log.Error(err)
+			}
+		}()
+	}
 	var previewWG sync.WaitGroup
 	var mainAppWG sync.WaitGroup
+	var sourcesWG sync.WaitGroup
 	mainAppWG.Add(1)
 	go func() {
 		defer mainAppWG.Done()
 		monkeyFunnelChan := make(chan engine.MonkeyStats, 1000*config.MaxRequests)
 		for i := uint(0); i < config.MaxRequests; i++ {
 
+			sourcesWG.Add(1)
 			go func() {
 				// ringer buffer is needed to supress too many logging of names.
 				inCh := make(chan interface{})
@@ -306,17 +604,33 @@ func main() {
 					}
 				}()
 
-				monkeyStatChan, statsDelta := engine.GenerateAndFilterMonkees(mainCtx, config.BatchSize, filter)
+				var monkeyStatChan <-chan engine.MonkeyStats
+				var statsDelta <-chan engine.Stats
+				if inputWallets != nil {
+					monkeyStatChan, statsDelta = engine.FilterMonkees(mainCtx, inputWallets, config.BatchSize, filter)
+				} else {
+					monkeyStatChan, statsDelta = engine.GenerateAndFilterMonkees(mainCtx, config.BatchSize, filter)
+				}
 				go func(monkeyStatsChan <-chan engine.MonkeyStats) {
+					defer sourcesWG.Done()
 					for monkey := range monkeyStatsChan {
+						if inputWallets != nil {
+							// every match from the input is worth a line, not just a name in passing
+							log.Infof("Input wallet %s matched the filters", monkey.PublicAddress)
+						}
 						inCh <- fmt.Sprintf("Say hi to %s", monkey.SillyName)
 						monkeyFunnelChan <- monkey
 					}
+					// ends the name logger
+					close(inCh)
 				}(monkeyStatChan)
 
 				go func(statsDeltaChan <-chan engine.Stats) {
 					for stats := range statsDeltaChan {
 						guiInstance.UpdateStats(stats)
+						if webhook != nil {
+							webhook.AddStats(stats)
+						}
 
 					}
 
@@ -326,20 +640,17 @@ func main() {
 			monkeyDisplayChan := make(chan engine.MonkeyStats, 1000*config.MaxRequests)
 			monkeyWriteDataChan := make(chan engine.MonkeyStats, 1000*config.MaxRequests)
 			go func() {
-				for {
+				// Finishing all writes is important, the funnel is only closed
+				// once every source handed over the monKeys it already matched.
+				defer close(monkeyWriteDataChan)
+				defer close(monkeyDisplayChan)
+				for monkey := range monkeyFunnelChan {
+					monkeyWriteDataChan <- monkey
+					// Skip if displaying is previews is backed up
 					select {
-					case <-mainCtx.Done():
-						// Finishing all writes is important
-						close(monkeyWriteDataChan)
-						return
-					case monkey := <-monkeyFunnelChan:
-						monkeyWriteDataChan <- monkey
-						// Skip if displaying is previews is backed up
-						select {
-						case monkeyDisplayChan <- monkey:
-							// log.Debug("preview sent")
-						default:
-						}
+					case monkeyDisplayChan <- monkey:
+						// log.Debug("preview sent")
+					default:
 					}
 				}
 			}()
@@ -347,7 +658,10 @@ func main() {
 			for i := 0; i < 10; i++ {
 				writeWG.Add(1)
 				go func() {
-					engine.OutputMonkeyData(targetDir, config.Format.String(), monkeyWriteDataChan)
+					err := engine.OutputMonkeys(sink, config.Format.String(), outputOptions, monkeyWriteDataChan)
+					if err != nil {
+						log.Error(err)
+					}
 					writeWG.Done()
 				}()
 			}
@@ -355,18 +669,33 @@ func main() {
 			for i := 0; i < 3; i++ {
 				previewWG.Add(1)
 				go func() {
-					gui.PreviewMonkeys(guiCtx, guiInstance.PNGPreviewChan(), monkeyDisplayChan)
+					gui.PreviewMonkeys(previewCtx, guiInstance.PNGPreviewChan(), monkeyDisplayChan)
 					previewWG.Done()
 				}()
 			}
 
 		}
+		go func() {
+			// sources stop generating once mainCtx is done but still hand over
+			// every monKey they matched, only then is the funnel drained
+			sourcesWG.Wait()
+			if inputWallets != nil {
+				log.Info("Every wallet from the input was tested.")
+				mainCancel()
+			}
+			close(monkeyFunnelChan)
+		}()
 		<-mainCtx.Done()
-		log.Infof("Total monKeys confirmed alive %d", guiInstance.GetFoundStat())
 		log.Info("Waiting for pending writes.")
 		writeWG.Wait()
+		log.Infof("Total monKeys confirmed alive %d", guiInstance.GetFoundStat())
+		err := sink.Close()
+		if err != nil {
+			log.Errorf("could not close the outputs: %s", err)
+		}
 		log.Info("Waiting for previews to end.")
-		writeWG.Wait()
+		previewCancel()
+		previewWG.Wait()
 		// Logging to gui can be out of order but these lines should be serial
 		if config.NoGui {
 			log.Infof("Raiding time up for looting them vain monKeys\nFind your monKeys and their wallets at %s.", targetDir)
@@ -383,6 +712,6 @@ func main() {
 	deadline, _ := mainCtx.Deadline()
 	log.Infof("Odds 1 out of %.2f", odds)
 	guiInstance.Run(deadline)
-	fmt.Println("Waiting for resources to clean up this could take a minute.")
+	fmt.Fprintln(os.Stderr, "Waiting for resources to clean up this could take a minute.")
 	mainAppWG.Wait()
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+type migrateCommand struct {
+	DryRun bool `long:"dry_run" description:"Only list the files that would be upgraded."`
+}
+
+var migrateCmd migrateCommand
+
+func (cmd *migrateCommand) Execute(args []string) error {
+	report, err := engine.MigrateDir(foundDirName, cmd.DryRun)
+	if err != nil {
+		return err
+	}
+	for _, fileName := range report.Migrated {
+		fmt.Println(fileName)
+	}
+	for _, problem := range report.Problems {
+		fmt.Println(problem)
+	}
+	verb := "Upgraded"
+	if cmd.DryRun {
+		verb = "Would upgrade"
+	}
+	log.Infof("%s %d monKey files to schema version %d, %d already were, skipped %d", verb, len(report.Migrated), engine.SchemaVersion, report.Current, report.Skipped)
+	if report.Skipped > 0 {
+		return fmt.Errorf("could not migrate %d monKey files", report.Skipped)
+	}
+	return nil
+}
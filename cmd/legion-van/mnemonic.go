@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+type mnemonicCommand struct {
+	Words string `long:"words" description:"Decode these 24 words back into a seed instead of reading a monKey file."`
+	Args  struct {
+		MonkeyFile string `positional-arg-name:"monkey.json" description:"Found monKey json file to convert the seed of."`
+	} `positional-args:"yes"`
+}
+
+var mnemonicCmd mnemonicCommand
+
+func (cmd *mnemonicCommand) Execute(args []string) error {
+	if cmd.Words != "" {
+		return cmd.decode()
+	}
+	if cmd.Args.MonkeyFile == "" {
+		return errors.New("a monKey json file or --words is required")
+	}
+	return cmd.encode()
+}
+
+func (cmd *mnemonicCommand) encode() error {
+	monkey, err := engine.LoadMonkeyFile(cmd.Args.MonkeyFile)
+	if err != nil {
+		return err
+	}
+	if monkey.EncryptedSecret != nil {
+		return fmt.Errorf("%s is encrypted, use the decrypt command to see its mnemonic", cmd.Args.MonkeyFile)
+	}
+	if monkey.KeyType != bananoutils.KeyTypeSeed {
+		return fmt.Errorf("%s has a %s key, only wallet seeds have a mnemonic", cmd.Args.MonkeyFile, monkey.KeyType)
+	}
+	seed, err := hex.DecodeString(monkey.PrivateKey.Reveal())
+	if err != nil {
+		return fmt.Errorf("could not decode seed: %w", err)
+	}
+	words, err := bananoutils.SeedToMnemonic(seed)
+	if err != nil {
+		return err
+	}
+	// Make sure the words will give the same seed back before anyone writes them down.
+	decoded, err := bananoutils.MnemonicToSeed(words)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(decoded, seed) {
+		return errors.New("mnemonic did not decode back into the same seed")
+	}
+	if monkey.Mnemonic != "" && monkey.Mnemonic.Reveal() != words {
+		return fmt.Errorf("mnemonic saved in %s does not match its seed", cmd.Args.MonkeyFile)
+	}
+	fmt.Println(words)
+	return nil
+}
+
+func (cmd *mnemonicCommand) decode() error {
+	seed, err := bananoutils.MnemonicToSeed(cmd.Words)
+	if err != nil {
+		return err
+	}
+	pub, _, err := bananoutils.KeypairFromSeed(bytes.NewReader(seed), 0)
+	if err != nil {
+		return err
+	}
+	account := bananoutils.PubKeyToAddress(pub)
+	fmt.Printf("seed:    %s\naddress: %s\n", hex.EncodeToString(seed), account)
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// passphraseEnv lets headless runs supply the passphrase without a prompt.
+const passphraseEnv = "LEGION_VAN_PASSPHRASE"
+
+// readPassphrase gets the passphrase from the environment or prompts for it
+// on the terminal without echo. When confirm is true the prompt is repeated
+// to catch typos before anything is encrypted with it.
+func readPassphrase(confirm bool) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+		return []byte(passphrase), nil
+	}
+	passphrase, err := promptPassphrase("Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+	again, err := promptPassphrase("Confirm passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, again) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func promptPassphrase(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+	if term.IsTerminal(fd) {
+		return term.ReadPassword(fd)
+	}
+	// not a terminal so there is nothing to echo to, read a single line.
+	line, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, fmt.Errorf("could not read passphrase: %w", err)
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+type proofCommand struct{}
+
+type proofSignCommand struct {
+	Message     string `long:"message" required:"yes" description:"Message to sign, for example your name and the date."`
+	ExpandedKey bool   `long:"expanded_key" description:"Sign with the expanded key combine printed for a split key monKey, it is asked for or read from LEGION_VAN_EXPANDED_KEY."`
+	Args        struct {
+		MonkeyFile string `positional-arg-name:"monkey.json" required:"yes" description:"Found monKey json file to sign with."`
+	} `positional-args:"yes"`
+}
+
+type proofVerifyCommand struct {
+	Address   string `long:"address" required:"yes" description:"Address the proof claims to be from."`
+	Message   string `long:"message" required:"yes" description:"Message that was signed."`
+	Signature string `long:"signature" required:"yes" description:"Signature printed by proof sign."`
+}
+
+var (
+	proofCmd       proofCommand
+	proofSignCmd   proofSignCommand
+	proofVerifyCmd proofVerifyCommand
+)
+
+func (cmd *proofSignCommand) Execute(args []string) error {
+	key, err := loadSigningKey(cmd.Args.MonkeyFile, cmd.ExpandedKey)
+	if err != nil {
+		return err
+	}
+	signature := key.signMessage(cmd.Message)
+	fmt.Printf("address:   %s\nmessage:   %s\nsignature: %s\n", key.address, cmd.Message, signature)
+	return nil
+}
+
+func (cmd *proofVerifyCommand) Execute(args []string) error {
+	valid, err := bananoutils.VerifyMessage(bananoutils.Account(cmd.Address), cmd.Message, bananoutils.Signature(cmd.Signature))
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("signature is NOT valid for this address and message")
+	}
+	fmt.Printf("signature is valid, %s signed the message\n", cmd.Address)
+	return nil
+}
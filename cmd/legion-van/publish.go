@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/bananoutils/rpc"
+)
+
+type publishCommand struct {
+	Args struct {
+		BlockFile string `positional-arg-name:"block.json" required:"yes" description:"Block json printed by the block command, - reads stdin."`
+	} `positional-args:"yes"`
+}
+
+var publishCmd publishCommand
+
+func (cmd *publishCommand) Execute(args []string) error {
+	if config.NodeRPC == "" {
+		return errors.New("publish needs a node, set --node_rpc")
+	}
+	var in io.Reader = os.Stdin
+	if cmd.Args.BlockFile != "-" {
+		file, err := os.Open(cmd.Args.BlockFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	var block bananoutils.StateBlock
+	err := json.NewDecoder(in).Decode(&block)
+	if err != nil {
+		return fmt.Errorf("could not parse block: %w", err)
+	}
+
+	// check locally first, the node errors are not very helpful
+	valid, err := block.VerifySignature()
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("block signature is not valid for its account")
+	}
+	var subtype string
+	switch {
+	case block.Previous == bananoutils.ZeroBlockHash:
+		subtype = "open"
+	case block.Link == bananoutils.ZeroBlockHash:
+		subtype = "change"
+	default:
+		return errors.New("only open and change blocks can be published")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client := rpc.NewClient(config.NodeRPC, nil)
+	hash, err := client.Process(ctx, &block, subtype)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("published %s block %s for %s\n", subtype, hash, block.Account)
+	return nil
+}
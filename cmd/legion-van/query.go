@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/steampoweredtaco/legion-van/index"
+)
+
+type queryCommand struct {
+	Session string `long:"session" description:"Only monKeys found in this session, the id is logged when a search starts."`
+	Sort    string `long:"sort" description:"Order of the monKeys, rarest first, newest first or by silly name." default:"rarity" choice:"rarity" choice:"found" choice:"name"`
+	Limit   int    `long:"limit" description:"Show at most this many monKeys."`
+	JSON    bool   `long:"json" description:"Print every monKey as a json line instead of a table."`
+	Scan    bool   `long:"scan" description:"First add the json files in foundMonKeys to the index, for monKeys found before it existed."`
+}
+
+var queryCmd queryCommand
+
+func (cmd *queryCommand) Execute(args []string) error {
+	// the vanity filters are parsed but not cleaned up yet
+	normalizeFilter()
+	fileName := filepath.Join(foundDirName, index.FileName)
+	if cmd.Scan {
+		err := scanFoundFiles(fileName)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		return fmt.Errorf("there is no index in %s yet, run a search or query --scan first", foundDirName)
+	}
+
+	found, err := index.Open(fileName, true, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer found.Close()
+	entries, err := found.Query(index.Query{Filter: filter, Session: cmd.Session, SortBy: cmd.Sort, Limit: cmd.Limit})
+	if err != nil {
+		return err
+	}
+
+	if cmd.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, entry := range entries {
+			err = encoder.Encode(entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ONE IN\tNAME\tADDRESS\tTRAITS\tFOUND\tFILE")
+	for _, entry := range entries {
+		var file string
+		if len(entry.Files) > 0 {
+			file = entry.Files[0]
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", oneInString(entry.OneIn), entry.SillyName, entry.Address,
+			traitsString(entry.Traits), entry.Found.Local().Format("2006-01-02 15:04"), file)
+	}
+	err = table.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d monKeys\n", len(entries))
+	return nil
+}
+
+// scanFoundFiles adds every monKey json file in foundMonKeys to the index.
+func scanFoundFiles(fileName string) error {
+	fileNames, err := filepath.Glob(filepath.Join(foundDirName, "*.json"))
+	if err != nil {
+		return err
+	}
+	found, err := index.Open(fileName, false, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer found.Close()
+	var skipped int
+	for _, monkeyFile := range fileNames {
+		err = found.AddFile(monkeyFile)
+		if err != nil {
+			skipped++
+			log.Warnf("could not index %s: %s", monkeyFile, err)
+		}
+	}
+	log.Infof("Indexed %d monKey files, skipped %d", len(fileNames)-skipped, skipped)
+	return nil
+}
+
+// traitsString lists the traits a monKey has, leaving out the empty ones.
+func traitsString(traits map[string]string) string {
+	var have []string
+	for category, trait := range traits {
+		if trait != "" && trait != "none" {
+			have = append(have, category+"="+trait)
+		}
+	}
+	sort.Strings(have)
+	return strings.Join(have, " ")
+}
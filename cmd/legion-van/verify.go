@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+	legionImage "github.com/steampoweredtaco/legion-van/image"
+)
+
+type verifyCommand struct {
+	Offline      bool `long:"offline" description:"Only check keys, missing files and manifests, leave out asking the monkey server for the traits and images again."`
+	Fix          bool `long:"fix" description:"Save missing images again from the monkey server in the --image_format format."`
+	NoPassphrase bool `long:"no_passphrase" description:"Do not ask for the passphrase, the keys of monKeys saved with --encrypt are not checked."`
+}
+
+var verifyCmd verifyCommand
+
+func (cmd *verifyCommand) Execute(args []string) error {
+	bananoutils.ChangeMonkeyServer(config.MonkeyServer)
+	// png images are compared and saved again with ImageMagick
+	if !cmd.Offline || cmd.Fix {
+		legionImage.Init()
+		defer legionImage.Destroy()
+	}
+	options := engine.VerifyOptions{
+		Offline:     cmd.Offline,
+		Fix:         cmd.Fix,
+		ImageFormat: config.Format.String(),
+	}
+	if !cmd.NoPassphrase {
+		options.Passphrase = func() ([]byte, error) {
+			return readPassphrase(false)
+		}
+	}
+	report, err := engine.VerifyDir(context.Background(), foundDirName, options)
+	if err != nil {
+		return err
+	}
+	for _, problem := range report.Problems {
+		fmt.Println(problem)
+	}
+	for _, fileName := range report.Fixed {
+		log.Infof("Saved missing image %s", fileName)
+	}
+	if report.Encrypted > 0 {
+		log.Warnf("The keys of %d encrypted monKeys were not checked", report.Encrypted)
+	}
+	log.Infof("Checked %d monKeys, %d problems, %d images saved again", report.Checked, len(report.Problems), len(report.Fixed))
+	if len(report.Problems) > 0 {
+		return fmt.Errorf("%s has %d problems", foundDirName, len(report.Problems))
+	}
+	return nil
+}
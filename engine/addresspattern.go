@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// AddressPattern is a text filter on the part of an address after ban_. It is
+// checked right after a key is made, so only addresses that spell something
+// are sent to the monkey server.
+type AddressPattern struct {
+	prefix string
+	suffix string
+	regex  *regexp.Regexp
+}
+
+// AddressPattern builds the address pattern of the filter, nil when the filter
+// has none.
+func (filter CmdLineFilter) AddressPattern() (*AddressPattern, error) {
+	if filter.AddressPrefix == "" && filter.AddressSuffix == "" && filter.AddressRegex == "" {
+		return nil, nil
+	}
+	pattern := &AddressPattern{
+		prefix: strings.ToLower(filter.AddressPrefix),
+		suffix: strings.ToLower(filter.AddressSuffix),
+	}
+	if !inAddressAlphabet(pattern.prefix) {
+		return nil, fmt.Errorf("address prefix %q has characters that are never in an address, only %s are", pattern.prefix, bananoutils.EncodeNano)
+	}
+	// the first character only holds the top bit of the key
+	if pattern.prefix != "" && pattern.prefix[0] != '1' && pattern.prefix[0] != '3' {
+		return nil, fmt.Errorf("address prefix %q must start with 1 or 3, no address starts with anything else", pattern.prefix)
+	}
+	if !inAddressAlphabet(pattern.suffix) {
+		return nil, fmt.Errorf("address suffix %q has characters that are never in an address, only %s are", pattern.suffix, bananoutils.EncodeNano)
+	}
+	if filter.AddressRegex != "" {
+		parsed, err := syntax.Parse(filter.AddressRegex, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("bad address regex: %w", err)
+		}
+		err = checkRegexLiterals(parsed)
+		if err != nil {
+			return nil, err
+		}
+		pattern.regex, err = regexp.Compile(filter.AddressRegex)
+		if err != nil {
+			return nil, fmt.Errorf("bad address regex: %w", err)
+		}
+	}
+	return pattern, nil
+}
+
+// Match reports whether address passes the pattern, a nil pattern passes
+// every address.
+func (pattern *AddressPattern) Match(address string) bool {
+	if pattern == nil {
+		return true
+	}
+	prefix, err := bananoutils.AddressPrefix(bananoutils.Account(address))
+	if err != nil {
+		return false
+	}
+	text := address[len(prefix):]
+	return strings.HasPrefix(text, pattern.prefix) &&
+		strings.HasSuffix(text, pattern.suffix) &&
+		(pattern.regex == nil || pattern.regex.MatchString(text))
+}
+
+// OneIn is one in how many random addresses pass the pattern. known is false
+// when a regex is part of it, its odds are not worked out.
+func (pattern *AddressPattern) OneIn() (oneIn float64, known bool) {
+	if pattern == nil {
+		return 1, true
+	}
+	oneIn = math.Pow(32, float64(len(pattern.prefix)+len(pattern.suffix)))
+	if pattern.prefix != "" {
+		// the first character is one of two, not one of 32
+		oneIn /= 16
+	}
+	return oneIn, pattern.regex == nil
+}
+
+func inAddressAlphabet(text string) bool {
+	for _, c := range text {
+		if !strings.ContainsRune(bananoutils.EncodeNano, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkRegexLiterals catches regexes asking for characters that never show up
+// in an address, like 0, 2, l or v, which would search forever.
+func checkRegexLiterals(re *syntax.Regexp) error {
+	if re.Op == syntax.OpLiteral {
+		for _, c := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 {
+				c = []rune(strings.ToLower(string(c)))[0]
+			}
+			if !strings.ContainsRune(bananoutils.EncodeNano, c) {
+				return fmt.Errorf("address regex has %q which is never in an address, only %s are", c, bananoutils.EncodeNano)
+			}
+		}
+	}
+	for _, sub := range re.Sub {
+		err := checkRegexLiterals(sub)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestAddressPatternValidation(t *testing.T) {
+	bad := []engine.CmdLineFilter{
+		{AddressPrefix: "taco"},
+		{AddressPrefix: "1ban0"},
+		{AddressSuffix: "lv"},
+		{AddressRegex: "("},
+		{AddressRegex: "^1.*lol$"},
+		{AddressRegex: "(?i)L"},
+	}
+	for _, filter := range bad {
+		if _, err := filter.AddressPattern(); err == nil {
+			t.Errorf("expected an error for %+v", filter)
+		}
+	}
+	pattern, err := engine.CmdLineFilter{}.AddressPattern()
+	if err != nil || pattern != nil {
+		t.Errorf("expected no pattern without address filters, got %v %v", pattern, err)
+	}
+}
+
+func TestAddressPatternMatch(t *testing.T) {
+	pattern, err := engine.CmdLineFilter{AddressPrefix: "3WTS", AddressSuffix: "7ba", AddressRegex: "duys"}.AddressPattern()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pattern.Match(testSeedAddress) {
+		t.Errorf("%s should match", testSeedAddress)
+	}
+	if !pattern.Match("nano_" + testSeedAddress[4:]) {
+		t.Errorf("nano_ prefix should match too")
+	}
+	if pattern.Match(testZeroAddress) {
+		t.Errorf("%s should not match", testZeroAddress)
+	}
+	if _, known := pattern.OneIn(); known {
+		t.Error("odds of a regex should not be known")
+	}
+
+	pattern, err = engine.CmdLineFilter{AddressPrefix: "3ab", AddressSuffix: "c"}.AddressPattern()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oneIn, known := pattern.OneIn(); !known || oneIn != 2*32*32*32 {
+		t.Errorf("expected 1 in %d, got 1 in %f", 2*32*32*32, oneIn)
+	}
+	if oneIn := engine.GetOdds(engine.CmdLineFilter{AddressPrefix: "3", Tail: []string{"tail"}}); oneIn != 2*5 {
+		t.Errorf("expected combined odds of 1 in 10, got 1 in %f", oneIn)
+	}
+}
+
+func TestGenerateOnlyPatternAddresses(t *testing.T) {
+	var mu sync.Mutex
+	var asked []string
+	newStubMonkeyServer(t, func(address string) map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		asked = append(asked, address)
+		return map[string]string{"hat": "crown"}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	monkeys, stats := engine.GenerateAndFilterMonkees(ctx, 10, engine.CmdLineFilter{AddressPrefix: "3", AddressSuffix: "a"})
+	go func() {
+		for range stats {
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		<-monkeys
+	}
+	cancel()
+	for range monkeys {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(asked) < 20 {
+		t.Fatalf("expected at least 20 addresses asked for, got %d", len(asked))
+	}
+	for _, address := range asked {
+		if !strings.HasPrefix(address, "ban_3") || !strings.HasSuffix(address, "a") {
+			t.Errorf("%s was sent to the monkey server without matching the pattern", address)
+		}
+	}
+}
+
+func TestFilterOnlyPatternAddresses(t *testing.T) {
+	var mu sync.Mutex
+	var asked []string
+	newStubMonkeyServer(t, func(address string) map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		asked = append(asked, address)
+		return map[string]string{"hat": "crown"}
+	})
+	input := strings.Join([]string{
+		testSeed,
+		`{"private_key": "` + testSeed + `", "key_type": "adhoc"}`,
+		`{"seed": "` + testZeroSeed + `"}`,
+	}, "\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wallets := engine.ReadWallets(ctx, strings.NewReader(input))
+	monkeys, stats := engine.FilterMonkees(ctx, wallets, 100, engine.CmdLineFilter{AddressPrefix: "3"})
+
+	var total, found uint64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for delta := range stats {
+			total += delta.Total
+			found += delta.Found
+		}
+	}()
+	for range monkeys {
+	}
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, address := range asked {
+		if address == testAdhocAddress {
+			t.Errorf("%s was sent to the monkey server without matching the pattern", address)
+		}
+	}
+	// the wallet the pattern left out still counts as tested
+	if total != 3 || found != 2 {
+		t.Errorf("expected 3 tested and 2 found, got %d and %d", total, found)
+	}
+}
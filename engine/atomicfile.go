@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// writeFileAtomic writes data to fileName so that a crash leaves either the
+// old file or the whole new one, never a partial file. The data is synced to
+// disk before it is renamed into place, and the rename before it returns.
+func writeFileAtomic(fileName string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(fileName)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := ioutil.TempFile(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	temp := file.Name()
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(temp)
+		}
+	}()
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Chmod(perm)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Rename(temp, fileName)
+	if err != nil {
+		return err
+	}
+	renamed = true
+	return syncDir(dir)
+}
+
+// syncDir makes a rename in dir survive a crash.
+func syncDir(dir string) error {
+	// windows cannot open directories to sync them, its renames are
+	// journaled anyway
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	handle, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	err = handle.Sync()
+	if err != nil {
+		return fmt.Errorf("could not sync %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// SchemaVersion is the version of the saved monKey json written by this
+// build, schema/monkey-v2.schema.json describes it. Files without a
+// schema_version are version 1, the raw reply of the monkey server with the
+// address and key added.
+const SchemaVersion = 2
+
+// MonkeyDocument is the saved json of a found monKey. Only the secret fields
+// of its key type are set, and none of them when the secret is encrypted.
+type MonkeyDocument struct {
+	SchemaVersion int                 `json:"schema_version"`
+	SillyName     string              `json:"silly_name"`
+	Address       string              `json:"address"`
+	KeyType       bananoutils.KeyType `json:"key_type"`
+	// Seed and Index are set for seed key types, the monKey is account Index
+	// of the wallet seed.
+	Seed  string  `json:"seed,omitempty"`
+	Index *uint32 `json:"index,omitempty"`
+	// PrivateKey is the ad-hoc private key, or the partial key of a split key
+	// search.
+	PrivateKey      string           `json:"private_key,omitempty"`
+	SplitBase       string           `json:"split_base,omitempty"`
+	Mnemonic        string           `json:"mnemonic,omitempty"`
+	EncryptedSecret *EncryptedSecret `json:"encrypted_secret,omitempty"`
+	Traits          MonkeyTraits     `json:"traits"`
+	// Odds is the chance of every known trait by its trait category, Rarity
+	// is one in how many monKeys look just like this one.
+	Odds         map[string]float64 `json:"odds"`
+	Rarity       float64            `json:"rarity"`
+	Session      string             `json:"session,omitempty"`
+	Found        time.Time          `json:"found"`
+	MonkeyServer string             `json:"monkey_server,omitempty"`
+}
+
+// MonkeyTraits are the traits of a monKey as the monkey server names them.
+type MonkeyTraits struct {
+	BackgroundColor string `json:"background_color"`
+	Glasses         string `json:"glasses"`
+	Hat             string `json:"hat"`
+	Misc            string `json:"misc"`
+	Mouth           string `json:"mouth"`
+	ShirtPants      string `json:"shirt_pants"`
+	Shoes           string `json:"shoes"`
+	Tail            string `json:"tail_accessory"`
+}
+
+// traitCategories are the odds categories of MonkeyOdds by their trait json
+// name.
+var traitCategories = map[string]string{
+	"glasses": "glasses",
+	"hat":     "hat",
+	"misc":    "misc",
+	"mouth":   "mouth",
+	"cloths":  "shirt_pants",
+	"feet":    "shoes",
+	"tail":    "tail_accessory",
+}
+
+// NewMonkeyDocument makes the saved json of monkey.
+func NewMonkeyDocument(monkey MonkeyStats) MonkeyDocument {
+	document := MonkeyDocument{
+		SchemaVersion: SchemaVersion,
+		SillyName:     monkey.SillyName,
+		Address:       monkey.PublicAddress,
+		KeyType:       monkey.KeyType,
+		SplitBase:     monkey.SplitBase,
+		Traits: MonkeyTraits{
+			BackgroundColor: monkey.BackgroundColor,
+			Glasses:         monkey.Glasses,
+			Hat:             monkey.Hat,
+			Misc:            monkey.Misc,
+			Mouth:           monkey.Mouth,
+			ShirtPants:      monkey.ShirtPants,
+			Shoes:           monkey.Shoes,
+			Tail:            monkey.Tail,
+		},
+		Odds:         make(map[string]float64),
+		Session:      monkey.Session,
+		Found:        monkey.Found.UTC(),
+		MonkeyServer: monkey.MonkeyServer,
+	}
+	if monkey.KeyType == bananoutils.KeyTypeSeed {
+		var index uint32
+		document.Index = &index
+	}
+	// never save the secrets next to their encrypted version
+	if monkey.EncryptedSecret != nil {
+		document.EncryptedSecret = monkey.EncryptedSecret
+	} else if monkey.KeyType == bananoutils.KeyTypeSeed {
+		document.Seed = monkey.PrivateKey.Reveal()
+		document.Mnemonic = monkey.Mnemonic.Reveal()
+	} else {
+		document.PrivateKey = monkey.PrivateKey.Reveal()
+	}
+
+	traits, oneIn := MonkeyOdds(monkey)
+	for _, trait := range traits {
+		if trait.Odds > 0 {
+			document.Odds[traitCategories[trait.Category]] = trait.Odds
+		}
+	}
+	document.Rarity = oneIn
+	return document
+}
+
+// Monkey is the monKey the document was made from.
+func (document MonkeyDocument) Monkey() MonkeyStats {
+	monkey := MonkeyStats{Additional: make(map[string]interface{})}
+	monkey.SillyName = document.SillyName
+	monkey.PublicAddress = document.Address
+	monkey.KeyType = document.KeyType
+	monkey.PrivateKey = bananoutils.Secret(document.PrivateKey)
+	if document.Seed != "" {
+		monkey.PrivateKey = bananoutils.Secret(document.Seed)
+	}
+	monkey.Mnemonic = bananoutils.Secret(document.Mnemonic)
+	monkey.SplitBase = document.SplitBase
+	monkey.EncryptedSecret = document.EncryptedSecret
+	monkey.BackgroundColor = document.Traits.BackgroundColor
+	monkey.Glasses = document.Traits.Glasses
+	monkey.Hat = document.Traits.Hat
+	monkey.Misc = document.Traits.Misc
+	monkey.Mouth = document.Traits.Mouth
+	monkey.ShirtPants = document.Traits.ShirtPants
+	monkey.Shoes = document.Traits.Shoes
+	monkey.Tail = document.Traits.Tail
+	monkey.Session = document.Session
+	monkey.Found = document.Found
+	monkey.MonkeyServer = document.MonkeyServer
+	return monkey
+}
+
+// documentVersion reads the schema_version of a saved monKey json.
+func documentVersion(data []byte) (int, error) {
+	var versioned struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	err := json.Unmarshal(data, &versioned)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse monKey json: %w", err)
+	}
+	if versioned.SchemaVersion == 0 {
+		return 1, nil
+	}
+	if versioned.SchemaVersion > SchemaVersion {
+		return 0, fmt.Errorf("schema_version %d is newer than this legion-van understands", versioned.SchemaVersion)
+	}
+	return versioned.SchemaVersion, nil
+}
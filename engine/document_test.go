@@ -0,0 +1,206 @@
+package engine_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestMonkeyDocument(t *testing.T) {
+	var monkey engine.MonkeyStats
+	monkey.SillyName = "Typed"
+	monkey.PublicAddress = testSeedAddress
+	monkey.PrivateKey = bananoutils.Secret(testSeed)
+	monkey.KeyType = bananoutils.KeyTypeSeed
+	monkey.Hat = "crown-[unique][w-0.225].svg"
+	monkey.Mouth = "cigar-[w-0.5].svg"
+	monkey.Session = "session-1"
+	monkey.Found = time.Date(2021, 5, 4, 3, 2, 1, 0, time.UTC)
+	data, err := json.MarshalIndent(monkey, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every saved field is in the schema and nothing the schema needs is left out
+	schemaData, err := ioutil.ReadFile(filepath.Join("..", "schema", "monkey-v2.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	err = json.Unmarshal(schemaData, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]json.RawMessage
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for field := range saved {
+		if _, ok := schema.Properties[field]; !ok {
+			t.Errorf("saved field %s is not in the schema", field)
+		}
+	}
+	for _, field := range schema.Required {
+		if _, ok := saved[field]; !ok {
+			t.Errorf("required field %s is not saved", field)
+		}
+	}
+	for _, secret := range []string{"private_key", "mnemonic", "encrypted_secret"} {
+		if _, ok := saved[secret]; ok {
+			t.Errorf("a plain seed must not save %s", secret)
+		}
+	}
+
+	var document engine.MonkeyDocument
+	err = json.Unmarshal(data, &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.SchemaVersion != engine.SchemaVersion || document.Seed != testSeed || document.Index == nil || *document.Index != 0 {
+		t.Errorf("unexpected document %s", data)
+	}
+	if math.Abs(document.Odds["hat"]-.35*48/4096) > 1e-12 || document.Rarity < 4096 {
+		t.Errorf("expected the odds of the crown, got %v one in %f", document.Odds, document.Rarity)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "Typed_"+testSeedAddress+".json")
+	err = ioutil.WriteFile(fileName, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := engine.LoadMonkeyFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.MonkeyBase != monkey.MonkeyBase {
+		t.Errorf("expected\n%+v\ngot\n%+v", monkey.MonkeyBase, loaded.MonkeyBase)
+	}
+
+	err = ioutil.WriteFile(fileName, []byte(`{"schema_version":3,"address":"`+testSeedAddress+`"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = engine.LoadMonkeyFile(fileName); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected a newer schema to be refused, got %v", err)
+	}
+}
+
+func TestMigrateDir(t *testing.T) {
+	dir := t.TempDir()
+	oldName := "Old_" + testSeedAddress + ".json"
+	oldData := []byte(`{"public_address":"` + testSeedAddress + `","private_key":"` + testSeed + `","hat":"crown-[unique][w-0.225].svg","mouth":"cigar-[w-0.5].svg","unknown_server_field":"dropped"}`)
+	err := ioutil.WriteFile(filepath.Join(dir, oldName), oldData, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSum := sha256.Sum256(oldData)
+	manifest := filepath.Join(dir, engine.ManifestName("session-1"))
+	err = ioutil.WriteFile(manifest, []byte(hex.EncodeToString(oldSum[:])+"  "+oldName+"\n"+strings.Repeat("0", 64)+"  Old_"+testSeedAddress+".png\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var current engine.MonkeyStats
+	current.PublicAddress = testAdhocAddress
+	current.PrivateKey = bananoutils.Secret(testSeed)
+	current.KeyType = bananoutils.KeyTypeAdhoc
+	currentData, err := json.Marshal(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "New_"+testAdhocAddress+".json"), currentData, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := engine.MigrateDir(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(report.Migrated, " ") != oldName || report.Current != 1 {
+		t.Fatalf("expected only %s to need migrating, got %+v", oldName, report)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, oldName)); string(data) != string(oldData) {
+		t.Fatal("a dry run must not change the file")
+	}
+
+	report, err = engine.MigrateDir(dir, false)
+	if err != nil || len(report.Migrated) != 1 {
+		t.Fatalf("expected %s to be migrated, got %+v %v", oldName, report, err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, oldName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document engine.MonkeyDocument
+	err = json.Unmarshal(data, &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.SchemaVersion != engine.SchemaVersion || document.SillyName != "Old" || document.KeyType != bananoutils.KeyTypeSeed ||
+		document.Seed != testSeed || document.Traits.Hat != "crown-[unique][w-0.225].svg" || document.Found.IsZero() {
+		t.Errorf("unexpected migrated monKey %s", data)
+	}
+	if strings.Contains(string(data), "unknown_server_field") {
+		t.Errorf("fields the schema does not have must not be saved: %s", data)
+	}
+	sums, err := engine.ReadManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSum := sha256.Sum256(data)
+	if sums[oldName] != hex.EncodeToString(newSum[:]) || sums["Old_"+testSeedAddress+".png"] != strings.Repeat("0", 64) {
+		t.Errorf("expected the manifest to have the new sum and keep the rest, got %v", sums)
+	}
+
+	report, err = engine.MigrateDir(dir, false)
+	if err != nil || len(report.Migrated) != 0 || report.Current != 2 {
+		t.Errorf("expected nothing left to migrate, got %+v %v", report, err)
+	}
+}
+
+func TestMigrateDirLeavesTamperedFiles(t *testing.T) {
+	dir := t.TempDir()
+	name := "Old_" + testSeedAddress + ".json"
+	listed := []byte(`{"public_address":"` + testSeedAddress + `","private_key":"` + testSeed + `"}`)
+	sum := sha256.Sum256(listed)
+	manifest := filepath.Join(dir, engine.ManifestName("session-1"))
+	manifestData := []byte(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	err := ioutil.WriteFile(manifest, manifestData, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// still a valid version 1 file, but not the one the manifest listed
+	tampered := []byte(`{"public_address":"` + testSeedAddress + `","private_key":"` + testSeed + `","hat":"crown-[unique][w-0.225].svg"}`)
+	err = ioutil.WriteFile(filepath.Join(dir, name), tampered, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := engine.MigrateDir(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Migrated) != 0 || report.Skipped != 1 || len(report.Problems) != 1 ||
+		report.Problems[0].File != name || report.Problems[0].Kind != engine.ProblemTampered {
+		t.Fatalf("expected %s to be skipped as tampered, got %+v", name, report)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, name)); string(data) != string(tampered) {
+		t.Error("a tampered file must not be migrated")
+	}
+	if data, _ := ioutil.ReadFile(manifest); string(data) != string(manifestData) {
+		t.Errorf("the manifest must keep the sum it listed, got %s", data)
+	}
+}
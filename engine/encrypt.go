@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	secretKDF    = "scrypt"
+	secretCipher = "xchacha20-poly1305"
+
+	// scrypt parameters recommended for interactive logins, each saved
+	// monKey stores them so they can be raised later without breaking old files.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	// files asking for more are refused before any key is derived, a
+	// crafted or broken encrypted_secret must not exhaust the memory
+	maxScryptN      = 1 << 20
+	maxScryptR      = 32
+	maxScryptP      = 16
+	maxScryptMemory = 1 << 30
+)
+
+var ErrDecrypt = errors.New("could not decrypt secret, wrong passphrase or the file was changed")
+
+// EncryptedSecret is what is saved in place of the private key and mnemonic
+// of a monKey when output encryption is enabled.
+type EncryptedSecret struct {
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       string `json:"salt"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// secretPayload is the plaintext sealed inside an EncryptedSecret.
+type secretPayload struct {
+	PrivateKey string `json:"private_key"`
+	Mnemonic   string `json:"mnemonic,omitempty"`
+}
+
+// SecretEncrypter seals monKey secrets with a key derived from a passphrase.
+// The key is derived once per run so encrypting a found monKey stays cheap,
+// every secret still gets its own nonce.
+type SecretEncrypter struct {
+	salt []byte
+	key  []byte
+}
+
+// NewSecretEncrypter derives an encryption key from passphrase with a fresh salt.
+func NewSecretEncrypter(passphrase []byte) (*SecretEncrypter, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("could not create salt: %w", err)
+	}
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive key: %w", err)
+	}
+	return &SecretEncrypter{salt: salt, key: key}, nil
+}
+
+// Encrypt seals the private key and mnemonic of monkey. The public address is
+// authenticated along with it so a secret cannot be swapped into another file.
+func (e *SecretEncrypter) Encrypt(monkey MonkeyStats) (*EncryptedSecret, error) {
+	aead, err := chacha20poly1305.NewX(e.key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(secretPayload{PrivateKey: monkey.PrivateKey.Reveal(), Mnemonic: monkey.Mnemonic.Reveal()})
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("could not create nonce: %w", err)
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, []byte(monkey.PublicAddress))
+	return &EncryptedSecret{
+		KDF:        secretKDF,
+		N:          scryptN,
+		R:          scryptR,
+		P:          scryptP,
+		Salt:       hex.EncodeToString(e.salt),
+		Cipher:     secretCipher,
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(ciphertext),
+	}, nil
+}
+
+// DecryptSecret reveals the private key and mnemonic of an encrypted monKey.
+func DecryptSecret(monkey MonkeyStats, passphrase []byte) (privateKey bananoutils.Secret, mnemonic bananoutils.Secret, err error) {
+	secret := monkey.EncryptedSecret
+	if secret == nil {
+		return "", "", errors.New("monKey secret is not encrypted")
+	}
+	if secret.KDF != secretKDF || secret.Cipher != secretCipher {
+		return "", "", fmt.Errorf("unsupported encryption %s with %s", secret.Cipher, secret.KDF)
+	}
+	if secret.N > maxScryptN || secret.R > maxScryptR || secret.P > maxScryptP || 128*int64(secret.N)*int64(secret.R) > maxScryptMemory {
+		return "", "", fmt.Errorf("scrypt parameters n=%d r=%d p=%d are too expensive", secret.N, secret.R, secret.P)
+	}
+	salt, err := hex.DecodeString(secret.Salt)
+	if err != nil {
+		return "", "", fmt.Errorf("bad salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(secret.Nonce)
+	if err != nil {
+		return "", "", fmt.Errorf("bad nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(secret.Ciphertext)
+	if err != nil {
+		return "", "", fmt.Errorf("bad ciphertext: %w", err)
+	}
+	key, err := scrypt.Key(passphrase, salt, secret.N, secret.R, secret.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return "", "", fmt.Errorf("could not derive key: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", "", err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return "", "", errors.New("bad nonce size")
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(monkey.PublicAddress))
+	if err != nil {
+		return "", "", ErrDecrypt
+	}
+	var payload secretPayload
+	err = json.Unmarshal(plaintext, &payload)
+	if err != nil {
+		return "", "", fmt.Errorf("could not parse secret: %w", err)
+	}
+	return bananoutils.Secret(payload.PrivateKey), bananoutils.Secret(payload.Mnemonic), nil
+}
@@ -0,0 +1,107 @@
+package engine_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestEncryptedOutputNeverWritesPlaintext(t *testing.T) {
+	newStubMonkeyServer(t, nil)
+	targetDir := t.TempDir()
+	passphrase := []byte("potassium")
+
+	seed, address, err := bananoutils.GeneratePrivateKeyAndFirstPublicAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedBytes, err := hex.DecodeString(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mnemonic, err := bananoutils.SeedToMnemonic(seedBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypter, err := engine.NewSecretEncrypter(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monkeys := make(chan engine.MonkeyStats, 1)
+	monkey := engine.MonkeyStats{Additional: map[string]interface{}{"hat": "crown"}}
+	monkey.PublicAddress = string(address)
+	monkey.PrivateKey = bananoutils.Secret(seed)
+	monkey.KeyType = bananoutils.KeyTypeSeed
+	monkey.SillyName = "Potassium"
+	monkeys <- monkey
+	close(monkeys)
+	engine.OutputMonkeyData(targetDir, "svg", engine.OutputOptions{Mnemonic: true, Encrypter: encrypter}, monkeys)
+
+	// Neither the seed, in any case, nor a run of its mnemonic may be on disk.
+	secrets := [][]byte{[]byte(seed), []byte(strings.ToUpper(seed)), seedBytes, []byte(strings.Join(strings.Fields(mnemonic)[:4], " "))}
+	var jsonFile string
+	err = filepath.Walk(targetDir, func(fileName string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		for _, secret := range secrets {
+			if bytes.Contains(data, secret) {
+				t.Errorf("%s contains a plaintext secret", fileName)
+			}
+		}
+		if filepath.Ext(fileName) == ".json" {
+			jsonFile = fileName
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsonFile == "" {
+		t.Fatal("monKey json was not written")
+	}
+
+	saved, err := engine.LoadMonkeyFile(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.PrivateKey != "" || saved.EncryptedSecret == nil {
+		t.Fatal("expected only an encrypted secret to be saved")
+	}
+	_, _, err = engine.DecryptSecret(saved, []byte("plantain"))
+	if !errors.Is(err, engine.ErrDecrypt) {
+		t.Errorf("expected decrypt error with the wrong passphrase, got %v", err)
+	}
+	// a crafted file cannot make decrypting allocate gigabytes
+	expensive := saved
+	expensive.EncryptedSecret = &engine.EncryptedSecret{}
+	*expensive.EncryptedSecret = *saved.EncryptedSecret
+	expensive.EncryptedSecret.N = 1 << 30
+	_, _, err = engine.DecryptSecret(expensive, passphrase)
+	if err == nil || !strings.Contains(err.Error(), "too expensive") {
+		t.Errorf("expected huge scrypt parameters to be refused, got %v", err)
+	}
+	privateKey, savedMnemonic, err := engine.DecryptSecret(saved, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if privateKey.Reveal() != seed {
+		t.Errorf("expected %s to be equal to %s", privateKey.Reveal(), seed)
+	}
+	if savedMnemonic.Reveal() != mnemonic {
+		t.Errorf("expected %s to be equal to %s", savedMnemonic.Reveal(), mnemonic)
+	}
+}
@@ -14,6 +14,10 @@ type CmdLineFilter struct {
 	Feet       []string `short:"F" description:"feet option. See --help-vanity for list"`
 	Tail       []string `short:"T" description:"tail option. See --help-vanity for list"`
 	Misc       []string `short:"M" description:"misc  option. See --help-vanity for list"`
+
+	AddressPrefix string `long:"address_prefix" description:"Address text right after ban_, always starts with 1 or 3."`
+	AddressSuffix string `long:"address_suffix" description:"Address text at the end of the address."`
+	AddressRegex  string `long:"address_regex" description:"Regular expression the address after ban_ has to match, addresses only use 13456789abcdefghijkmnopqrstuwxyz."`
 }
 
 func fitlerMatchAny(options []string, s string) bool {
@@ -39,290 +43,145 @@ func matchFilters(monkey MonkeyStats, filter CmdLineFilter) bool {
 		fitlerMatchAny(filter.Tail, monkey.Tail)
 
 }
-func glassesOdds(prefixes []string) (odds float64) {
-	if len(prefixes) == 0 {
-		odds = 1
-		return
-	}
-	glassesOdds := 0
-	for _, prefix := range prefixes {
-		if strings.HasPrefix("glasses-nerd-green-[w-1].svg", prefix) {
-			glassesOdds += 512
-		}
-		if strings.HasPrefix("sunglasses-aviator-yellow-[removes-eyes][w-1].svg", prefix) {
-			glassesOdds += 512
-		}
-		if strings.HasPrefix("sunglasses-thug-[removes-eyes][w-1].svg", prefix) {
-			glassesOdds += 520
-		}
-		if strings.HasPrefix("eye-patch-[w-0.5].svg", prefix) {
-			glassesOdds += 256
-		}
-		if strings.HasPrefix("glasses-nerd-cyan-[w-1].svg", prefix) {
-			glassesOdds += 512
-		}
-		if strings.HasPrefix("glasses-nerd-pink-[w-1].svg", prefix) {
-			glassesOdds += 512
-		}
-		if strings.HasPrefix("monocle-[w-0.5].svg", prefix) {
-			glassesOdds += 256
-		}
-		if strings.HasPrefix("sunglasses-aviator-cyan-[removes-eyes][w-1].svg", prefix) {
-			glassesOdds += 512
-		}
-		if strings.HasPrefix("sunglasses-aviator-green-[removes-eyes][w-1].svg", prefix) {
-			glassesOdds += 512
-		}
-	}
-	odds = .25 * float64(glassesOdds) / 4096.0
-	return
-}
 
-func hatsOdds(prefixes []string) (odds float64) {
-	if len(prefixes) == 0 {
-		odds = 1
-		return
-	}
-	hatsOdds := 0
-	for _, prefix := range prefixes {
-		if strings.HasPrefix("beanie-long-[colorable-random][w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("cap-banano-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("cap-kappa-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("cap-smug-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("hat-jester-[unique][w-0.125].svg", prefix) {
-			hatsOdds += 27
-		}
-		if strings.HasPrefix("bandana-[w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("cap-carlos-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("crown-[unique][w-0.225].svg", prefix) {
-			hatsOdds += 48
-		}
-		if strings.HasPrefix("fedora-long-[w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("cap-hng-plus-[unique][w-0.125].svg", prefix) {
-			hatsOdds += 27
-		}
-		if strings.HasPrefix("fedora-[w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("beanie-banano-[w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("beanie-hippie-[unique][w-0.125].svg", prefix) {
-			hatsOdds += 27
-		}
-		if strings.HasPrefix("cap-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("cap-backwards-[w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("cap-bebe-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("cap-hng-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("hat-cowboy-[w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("helmet-viking-[w-1].svg", prefix) {
-			hatsOdds += 224
-		}
-		if strings.HasPrefix("beanie-[w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("beanie-long-banano-[colorable-random][w-1].svg", prefix) {
-			hatsOdds += 212
-		}
-		if strings.HasPrefix("cap-pepe-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("cap-rick-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("cap-smug-green-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-		if strings.HasPrefix("cap-thonk-[w-0.8].svg", prefix) {
-			hatsOdds += 169
-		}
-	}
-	odds = .35 * float64(hatsOdds) / 4096.0
-	return
+// traitWeight is how often a trait file is picked out of 4096 once the monKey
+// has a trait of its category.
+type traitWeight struct {
+	name   string
+	weight int
 }
-func miscOdds(prefixes []string) (odds float64) {
-	if len(prefixes) == 0 {
-		odds = 1
-		return
-	}
-	miscOdds := 0
-	for _, prefix := range prefixes {
-		if strings.HasPrefix("banana-right-hand-[above-hands][removes-hand-right][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("camera-[above-shirts-pants][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("club-[above-hands][removes-hands][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("flamethrower-[removes-hands][above-hands][w-0.04].svg", prefix) {
-			miscOdds += 15
-		}
-		if strings.HasPrefix("guitar-[above-hands][removes-left-hand][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("microphone-[above-hands][removes-hand-right][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("necklace-boss-[above-shirts-pants][w-0.75].svg", prefix) {
-			miscOdds += 273
-		}
-		if strings.HasPrefix("tie-pink-[above-shirts-pants][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("whisky-right-[above-hands][removes-hand-right][w-0.5].svg", prefix) {
-			miscOdds += 190
-		}
-		if strings.HasPrefix("banana-hands-[above-hands][removes-hands][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("bowtie-[above-hands][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("gloves-white-[above-hands][removes-hands][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-		if strings.HasPrefix("tie-cyan-[above-shirts-pants][w-1].svg", prefix) {
-			miscOdds += 363
-		}
-	}
-	odds = .3 * float64(miscOdds) / 4096.0
-	return
-}
-func mouthOdds(prefixes []string) (odds float64) {
-	if len(prefixes) == 0 {
-		odds = 1
-		return
-	}
-	mouthOdds := 0
-	for _, prefix := range prefixes {
 
-		if strings.HasPrefix("smile-tongue-[w-0.5].svg", prefix) {
-			mouthOdds += 372
-		}
-		if strings.HasPrefix("cigar-[w-0.5].svg", prefix) {
-			mouthOdds += 369
-		}
-		if strings.HasPrefix("confused-[w-1].svg", prefix) {
-			mouthOdds += 737
-		}
-		if strings.HasPrefix("joint-[unique][w-0.06].svg", prefix) {
-			mouthOdds += 45
-		}
-		if strings.HasPrefix("meh-[w-1].svg", prefix) {
-			mouthOdds += 737
-		}
-		if strings.HasPrefix("pipe-[w-0.5].svg", prefix) {
-			mouthOdds += 369
-		}
-		if strings.HasPrefix("smile-big-teeth-[w-1].svg", prefix) {
-			mouthOdds += 737
-		}
-		if strings.HasPrefix("smile-normal-[w-1].svg", prefix) {
-			mouthOdds += 737
-		}
-	}
-	odds = float64(mouthOdds) / 4096.0
-	return
+// traitTable is the chance a monKey has any trait of a category at all and the
+// weights of every trait in it.
+type traitTable struct {
+	chance  float64
+	weights []traitWeight
 }
-func clothsOdds(prefixes []string) (odds float64) {
+
+// most of this was generated from hacked version of the monkey server.
+var (
+	glassesTable = traitTable{.25, []traitWeight{
+		{"glasses-nerd-green-[w-1].svg", 512},
+		{"sunglasses-aviator-yellow-[removes-eyes][w-1].svg", 512},
+		{"sunglasses-thug-[removes-eyes][w-1].svg", 520},
+		{"eye-patch-[w-0.5].svg", 256},
+		{"glasses-nerd-cyan-[w-1].svg", 512},
+		{"glasses-nerd-pink-[w-1].svg", 512},
+		{"monocle-[w-0.5].svg", 256},
+		{"sunglasses-aviator-cyan-[removes-eyes][w-1].svg", 512},
+		{"sunglasses-aviator-green-[removes-eyes][w-1].svg", 512},
+	}}
+	hatsTable = traitTable{.35, []traitWeight{
+		{"beanie-long-[colorable-random][w-1].svg", 212},
+		{"cap-banano-[w-0.8].svg", 169},
+		{"cap-kappa-[w-0.8].svg", 169},
+		{"cap-smug-[w-0.8].svg", 169},
+		{"hat-jester-[unique][w-0.125].svg", 27},
+		{"bandana-[w-1].svg", 212},
+		{"cap-carlos-[w-0.8].svg", 169},
+		{"crown-[unique][w-0.225].svg", 48},
+		{"fedora-long-[w-1].svg", 212},
+		{"cap-hng-plus-[unique][w-0.125].svg", 27},
+		{"fedora-[w-1].svg", 212},
+		{"beanie-banano-[w-1].svg", 212},
+		{"beanie-hippie-[unique][w-0.125].svg", 27},
+		{"cap-[w-0.8].svg", 169},
+		{"cap-backwards-[w-1].svg", 212},
+		{"cap-bebe-[w-0.8].svg", 169},
+		{"cap-hng-[w-0.8].svg", 169},
+		{"hat-cowboy-[w-1].svg", 212},
+		{"helmet-viking-[w-1].svg", 224},
+		{"beanie-[w-1].svg", 212},
+		{"beanie-long-banano-[colorable-random][w-1].svg", 212},
+		{"cap-pepe-[w-0.8].svg", 169},
+		{"cap-rick-[w-0.8].svg", 169},
+		{"cap-smug-green-[w-0.8].svg", 169},
+		{"cap-thonk-[w-0.8].svg", 169},
+	}}
+	miscTable = traitTable{.3, []traitWeight{
+		{"banana-right-hand-[above-hands][removes-hand-right][w-1].svg", 363},
+		{"camera-[above-shirts-pants][w-1].svg", 363},
+		{"club-[above-hands][removes-hands][w-1].svg", 363},
+		{"flamethrower-[removes-hands][above-hands][w-0.04].svg", 15},
+		{"guitar-[above-hands][removes-left-hand][w-1].svg", 363},
+		{"microphone-[above-hands][removes-hand-right][w-1].svg", 363},
+		{"necklace-boss-[above-shirts-pants][w-0.75].svg", 273},
+		{"tie-pink-[above-shirts-pants][w-1].svg", 363},
+		{"whisky-right-[above-hands][removes-hand-right][w-0.5].svg", 190},
+		{"banana-hands-[above-hands][removes-hands][w-1].svg", 363},
+		{"bowtie-[above-hands][w-1].svg", 363},
+		{"gloves-white-[above-hands][removes-hands][w-1].svg", 363},
+		{"tie-cyan-[above-shirts-pants][w-1].svg", 363},
+	}}
+	mouthTable = traitTable{1, []traitWeight{
+		{"smile-tongue-[w-0.5].svg", 372},
+		{"cigar-[w-0.5].svg", 369},
+		{"confused-[w-1].svg", 737},
+		{"joint-[unique][w-0.06].svg", 45},
+		{"meh-[w-1].svg", 737},
+		{"pipe-[w-0.5].svg", 369},
+		{"smile-big-teeth-[w-1].svg", 737},
+		{"smile-normal-[w-1].svg", 737},
+	}}
+	clothsTable = traitTable{.25, []traitWeight{
+		{"overalls-blue[w-1].svg", 683},
+		{"overalls-red[w-1].svg", 683},
+		{"pants-business-blue-[removes-legs][w-1].svg", 683},
+		{"pants-flower-[removes-legs][w-1].svg", 683},
+		{"tshirt-long-stripes-[colorable-random][w-1].svg", 683},
+		{"tshirt-short-white[w-1].svg", 686},
+	}}
+	feetTable = traitTable{.22, []traitWeight{
+		{"socks-v-stripe-[colorable-random][removes-feet][w-1].svg", 686},
+		{"sneakers-blue-[removes-feet][w-1].svg", 683},
+		{"sneakers-green-[removes-feet][w-1].svg", 683},
+		{"sneakers-red-[removes-feet][w-1].svg", 683},
+		{"sneakers-swagger-[removes-feet][w-1].svg", 683},
+		{"socks-h-stripe-[removes-feet][w-1].svg", 683},
+	}}
+	tailsTable = traitTable{.2, []traitWeight{
+		{"tail-sock-[colorable-random][w-1].svg", 4096},
+	}}
+)
+
+// odds is the chance a monKey has a trait of the table matching any of
+// prefixes, no prefixes always match.
+func (table traitTable) odds(prefixes []string) float64 {
 	if len(prefixes) == 0 {
-		odds = 1
-		return
+		return 1
 	}
-	shirtOdds := 0
+	weight := 0
 	for _, prefix := range prefixes {
-
-		if strings.HasPrefix("overalls-blue[w-1].svg", prefix) {
-			shirtOdds += 683
-		}
-		if strings.HasPrefix("overalls-red[w-1].svg", prefix) {
-			shirtOdds += 683
-		}
-		if strings.HasPrefix("pants-business-blue-[removes-legs][w-1].svg", prefix) {
-			shirtOdds += 683
-		}
-		if strings.HasPrefix("pants-flower-[removes-legs][w-1].svg", prefix) {
-			shirtOdds += 683
-		}
-		if strings.HasPrefix("tshirt-long-stripes-[colorable-random][w-1].svg", prefix) {
-			shirtOdds += 683
-		}
-		if strings.HasPrefix("tshirt-short-white[w-1].svg", prefix) {
-			shirtOdds += 686
+		for _, trait := range table.weights {
+			if strings.HasPrefix(trait.name, prefix) {
+				weight += trait.weight
+			}
 		}
 	}
-	odds = .25 * float64(shirtOdds) / 4096.0
-	return
+	return table.chance * float64(weight) / 4096.0
 }
-func feetOdds(prefixes []string) (odds float64) {
-	if len(prefixes) == 0 {
-		odds = 1
-		return
+
+// traitOdds is the chance a monKey has exactly trait, as named by the monkey
+// server with or without the weight tags. ok is false for unknown traits.
+func (table traitTable) traitOdds(trait string) (odds float64, ok bool) {
+	if trait == "" || trait == "none" {
+		return 1 - table.chance, table.chance < 1
 	}
-	shoeOdds := 0
-	for _, prefix := range prefixes {
-		if strings.HasPrefix("socks-v-stripe-[colorable-random][removes-feet][w-1].svg", prefix) {
-			shoeOdds += 686
-		}
-		if strings.HasPrefix("sneakers-blue-[removes-feet][w-1].svg", prefix) {
-			shoeOdds += 683
-		}
-		if strings.HasPrefix("sneakers-green-[removes-feet][w-1].svg", prefix) {
-			shoeOdds += 683
-		}
-		if strings.HasPrefix("sneakers-red-[removes-feet][w-1].svg", prefix) {
-			shoeOdds += 683
-		}
-		if strings.HasPrefix("sneakers-swagger-[removes-feet][w-1].svg", prefix) {
-			shoeOdds += 683
-		}
-		if strings.HasPrefix("socks-h-stripe-[removes-feet][w-1].svg", prefix) {
-			shoeOdds += 683
+	for _, known := range table.weights {
+		if known.name == trait || traitName(known.name) == trait {
+			return table.chance * float64(known.weight) / 4096.0, true
 		}
 	}
-	odds = .22 * float64(shoeOdds) / 4096.0
-	return
+	return 0, false
 }
-func tailsOdds(prefixes []string) (odds float64) {
-	if len(prefixes) == 0 {
-		odds = 1
-		return
-	}
-	tailsOdds := 0
-	for _, prefix := range prefixes {
-		if strings.HasPrefix("tail-sock-[colorable-random][w-1].svg", prefix) {
-			tailsOdds += 4096
-		}
+
+// traitName strips the weight tags and extension from a trait file name.
+func traitName(fileName string) string {
+	name := strings.TrimSuffix(fileName, ".svg")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
 	}
-	// yes this could be just .2 but it is code generated, come on
-	odds = .2 * float64(tailsOdds) / 4096.0
-	return
+	return strings.TrimSuffix(name, "-")
 }
 
 func getSmallestPrefixes(filters []string) []string {
@@ -356,18 +215,53 @@ func SimplifyFilters(filter *CmdLineFilter) {
 }
 
 func GetOdds(filter CmdLineFilter) float64 {
+	totalOdds := glassesTable.odds(filter.Glasses) *
+		hatsTable.odds(filter.Hat) *
+		miscTable.odds(filter.Misc) *
+		mouthTable.odds(filter.Mouth) *
+		clothsTable.odds(filter.Cloths) *
+		feetTable.odds(filter.Feet) *
+		tailsTable.odds(filter.Tail)
+	// an invalid pattern is reported before any search starts
+	pattern, _ := filter.AddressPattern()
+	patternOneIn, _ := pattern.OneIn()
+	return patternOneIn / totalOdds
+}
 
-	// most of this was generated from hacked version of the monkey server.
-	gOdds := glassesOdds(filter.Glasses)
-	hOdds := hatsOdds(filter.Hat)
-	mOdds := miscOdds(filter.Misc)
-	oOdds := mouthOdds(filter.Mouth)
-	cOdds := clothsOdds(filter.Cloths)
-	fOdds := feetOdds(filter.Feet)
-	tOdds := tailsOdds(filter.Tail)
+// TraitOdds is the chance of one trait of a monKey.
+type TraitOdds struct {
+	Category string
+	Trait    string
+	// Odds is the chance a random monKey has the trait, 0 when the trait is
+	// not known.
+	Odds float64
+}
 
-	totalOdds := gOdds * hOdds * mOdds * oOdds * cOdds * tOdds * fOdds
-	return 1 / totalOdds
+// MonkeyOdds returns the chance of every trait of monkey and one in how many
+// monKeys look just like it, unknown traits are left out of the total.
+func MonkeyOdds(monkey MonkeyStats) (traits []TraitOdds, oneIn float64) {
+	categories := []struct {
+		name  string
+		trait string
+		table traitTable
+	}{
+		{"glasses", monkey.Glasses, glassesTable},
+		{"hat", monkey.Hat, hatsTable},
+		{"misc", monkey.Misc, miscTable},
+		{"mouth", monkey.Mouth, mouthTable},
+		{"cloths", monkey.ShirtPants, clothsTable},
+		{"feet", monkey.Shoes, feetTable},
+		{"tail", monkey.Tail, tailsTable},
+	}
+	total := 1.0
+	for _, category := range categories {
+		odds, ok := category.table.traitOdds(category.trait)
+		if ok {
+			total *= odds
+		}
+		traits = append(traits, TraitOdds{Category: category.name, Trait: category.trait, Odds: odds})
+	}
+	return traits, 1 / total
 }
 
 func NewRingBuffer(inCh, outCh chan interface{}) *RingBuffer {
@@ -0,0 +1,86 @@
+package engine_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestGetOdds(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter engine.CmdLineFilter
+		oneIn  float64
+	}{
+		{"no filter", engine.CmdLineFilter{}, 1},
+		{"crown", engine.CmdLineFilter{Hat: []string{"crown"}}, 4096 / (.35 * 48)},
+		{"any cap", engine.CmdLineFilter{Hat: []string{"cap"}}, 4096 / (.35 * (169*11 + 27 + 212))},
+		{"flamethrower or camera and a cap",
+			engine.CmdLineFilter{Misc: []string{"flamethrower", "camera"}, Hat: []string{"cap"}},
+			4096 / (.3 * (15 + 363)) * 4096 / (.35 * (169*11 + 27 + 212))},
+		{"tail", engine.CmdLineFilter{Tail: []string{"tail"}}, 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oneIn := engine.GetOdds(test.filter)
+			if math.Abs(oneIn-test.oneIn) > 1e-6*test.oneIn {
+				t.Errorf("expected 1 in %f, got 1 in %f", test.oneIn, oneIn)
+			}
+		})
+	}
+}
+
+func TestMonkeyOdds(t *testing.T) {
+	newStubMonkeyServer(t, func(string) map[string]string {
+		return map[string]string{
+			"glasses":        "none",
+			"hat":            "crown",
+			"misc":           "flamethrower-[removes-hands][above-hands][w-0.04].svg",
+			"mouth":          "cigar",
+			"shirt_pants":    "none",
+			"shoes":          "none",
+			"tail_accessory": "something-new",
+		}
+	})
+	address := "ban_3wtsduys8b7jkbfwwfzx3jgpgpsi9b8zurfe9bp1p5cdxkqiz7a5wxcoo7ba"
+	monkey, err := engine.FetchMonkeyStats(context.Background(), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if monkey.PublicAddress != address || monkey.Hat != "crown" {
+		t.Fatalf("unexpected monKey %+v", monkey.MonkeyBase)
+	}
+
+	traits, oneIn := engine.MonkeyOdds(monkey)
+	want := map[string]float64{
+		"glasses": .75,
+		"hat":     .35 * 48 / 4096,
+		"misc":    .3 * 15 / 4096,
+		"mouth":   369.0 / 4096,
+		"cloths":  .75,
+		"feet":    .78,
+		"tail":    0,
+	}
+	total := 1.0
+	for _, trait := range traits {
+		odds, ok := want[trait.Category]
+		if !ok {
+			t.Errorf("unexpected category %s", trait.Category)
+			continue
+		}
+		if math.Abs(trait.Odds-odds) > 1e-9 {
+			t.Errorf("%s %s: expected %f, got %f", trait.Category, trait.Trait, odds, trait.Odds)
+		}
+		if odds > 0 {
+			total *= odds
+		}
+	}
+	if len(traits) != len(want) {
+		t.Errorf("expected %d traits, got %d", len(want), len(traits))
+	}
+	if math.Abs(oneIn-1/total) > 1e-6/total {
+		t.Errorf("expected 1 in %f, got 1 in %f", 1/total, oneIn)
+	}
+}
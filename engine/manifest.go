@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ManifestName is the file name of the sha256 manifest of session.
+func ManifestName(session string) string {
+	return "legion-van-" + session + ".sha256"
+}
+
+type manifestSink struct {
+	dir string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewManifestSink lists the sha256 of every file the directory sink saves in
+// dir in the manifest of the session, in the format of sha256sum so it can be
+// checked with the verify command or sha256sum -c. A line is synced for each
+// monKey as it is found.
+func NewManifestSink(dir string) Sink {
+	return &manifestSink{dir: dir}
+}
+
+func (sink *manifestSink) Open(session string) error {
+	err := os.MkdirAll(sink.dir, 0700)
+	if err != nil {
+		return fmt.Errorf("could not create directory: %w", err)
+	}
+	sink.file, err = os.OpenFile(path.Join(sink.dir, ManifestName(session)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create manifest: %w", err)
+	}
+	return nil
+}
+
+func (sink *manifestSink) Write(found FoundMonkey) error {
+	var lines strings.Builder
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{found.FileName() + ".json", found.JSON},
+		{found.FileName() + found.ImageExtension, found.Image},
+	} {
+		if entry.data == nil {
+			continue
+		}
+		sum := sha256.Sum256(entry.data)
+		fmt.Fprintf(&lines, "%s  %s\n", hex.EncodeToString(sum[:]), entry.name)
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	_, err := sink.file.WriteString(lines.String())
+	if err == nil {
+		err = sink.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("could not add monKey %s to the manifest: %w", found.Monkey.SillyName, err)
+	}
+	return nil
+}
+
+func (sink *manifestSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.file == nil {
+		return nil
+	}
+	err := sink.file.Close()
+	if err != nil {
+		return fmt.Errorf("could not finish manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest reads the file names and hex sha256 sums of a manifest.
+func ReadManifest(fileName string) (map[string]string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+	defer file.Close()
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "  ", 2)
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("%s line %d is not a sha256 sum", fileName, lineNumber)
+		}
+		sums[fields[1]] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+	return sums, nil
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MigrateReport is what MigrateDir did.
+type MigrateReport struct {
+	// Migrated are the files upgraded to SchemaVersion, relative to the
+	// directory.
+	Migrated []string
+	Current  int
+	// Skipped counts the files that could not be migrated, Problems has the
+	// ones left alone because they no longer match a manifest.
+	Skipped  int
+	Problems []Problem
+}
+
+// sumChange is the sha256 of a migrated file before and after.
+type sumChange struct {
+	old, new string
+}
+
+// MigrateDir upgrades the monKey json files in dir and its pending directory
+// to SchemaVersion. Files of older versions never had a session, they are
+// found when they were last changed. A file that changed since a manifest
+// listed it is not migrated, so the verify command can still tell, and the
+// manifests listing a migrated file get its new sha256. With dryRun nothing
+// is written.
+func MigrateDir(dir string, dryRun bool) (MigrateReport, error) {
+	var report MigrateReport
+	fileNames, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return report, err
+	}
+	pendingNames, err := filepath.Glob(filepath.Join(dir, PendingDirName, "*.json"))
+	if err != nil {
+		return report, err
+	}
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.sha256"))
+	if err != nil {
+		return report, err
+	}
+	manifestSums := make(map[string]map[string]string, len(manifests))
+	for _, manifest := range manifests {
+		manifestSums[manifest], err = ReadManifest(manifest)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	sums := make(map[string]sumChange)
+files:
+	for _, fileName := range append(fileNames, pendingNames...) {
+		name, _ := filepath.Rel(dir, fileName)
+		data, oldSum, err := migrateMonkeyFile(fileName)
+		if err != nil {
+			report.Skipped++
+			log.Warnf("could not migrate %s: %s", name, err)
+			continue
+		}
+		if data == nil {
+			report.Current++
+			continue
+		}
+		for _, manifest := range manifests {
+			if sum, ok := manifestSums[manifest][name]; ok && sum != oldSum {
+				report.Skipped++
+				report.Problems = append(report.Problems, Problem{File: name, Kind: ProblemTampered, Detail: "changed since it was listed in " + filepath.Base(manifest) + ", not migrated"})
+				continue files
+			}
+		}
+		report.Migrated = append(report.Migrated, name)
+		if dryRun {
+			continue
+		}
+		err = writeFileAtomic(fileName, data, 0600)
+		if err != nil {
+			return report, fmt.Errorf("could not save migrated %s: %w", name, err)
+		}
+		sum := sha256.Sum256(data)
+		sums[name] = sumChange{old: oldSum, new: hex.EncodeToString(sum[:])}
+	}
+	if dryRun || len(sums) == 0 {
+		return report, nil
+	}
+
+	for _, manifest := range manifests {
+		err = updateManifest(manifest, sums)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// migrateMonkeyFile returns the json of fileName upgraded to SchemaVersion and
+// the sha256 of the file as it is, the json is nil when it already is.
+func migrateMonkeyFile(fileName string) ([]byte, string, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	oldSum := hex.EncodeToString(sum[:])
+	version, err := documentVersion(data)
+	if err != nil {
+		return nil, oldSum, err
+	}
+	if version == SchemaVersion {
+		return nil, oldSum, nil
+	}
+	monkey, err := LoadMonkeyFile(fileName)
+	if err != nil {
+		return nil, oldSum, err
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return nil, oldSum, err
+	}
+	monkey.Found = info.ModTime()
+	data, err = json.MarshalIndent(monkey, "", "  ")
+	if err != nil {
+		return nil, oldSum, err
+	}
+	return data, oldSum, nil
+}
+
+// updateManifest replaces the sums of the files in sums listed in manifest
+// with their new sum, only where it still has their old one. The other lines
+// are kept as they are.
+func updateManifest(manifest string, sums map[string]sumChange) error {
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		return fmt.Errorf("could not read manifest: %w", err)
+	}
+	var updated bytes.Buffer
+	changed := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.SplitN(line, "  ", 2)
+		if sum, ok := sums[fields[len(fields)-1]]; ok && len(fields) == 2 && fields[0] == sum.old {
+			line = sum.new + "  " + fields[1]
+			changed = true
+		}
+		updated.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read manifest: %w", err)
+	}
+	if !changed {
+		return nil
+	}
+	err = writeFileAtomic(manifest, updated.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("could not update manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// LoadMonkeyFile reads a monKey json file previously saved by OutputMonkeyData,
+// of any schema version.
+func LoadMonkeyFile(fileName string) (monkey MonkeyStats, err error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return monkey, fmt.Errorf("could not read monKey file: %w", err)
+	}
+	version, err := documentVersion(data)
+	if err != nil {
+		return monkey, err
+	}
+	if version == 1 {
+		monkey, err = parseVersion1(data)
+	} else {
+		var document MonkeyDocument
+		err = json.Unmarshal(data, &document)
+		if err != nil {
+			return monkey, fmt.Errorf("could not parse monKey json: %w", err)
+		}
+		monkey = document.Monkey()
+	}
+	if err != nil {
+		return monkey, err
+	}
+
+	// files saved before key types existed are always seeds
+	if monkey.KeyType == "" {
+		monkey.KeyType = bananoutils.KeyTypeSeed
+	}
+	if monkey.PublicAddress == "" {
+		return monkey, fmt.Errorf("%s is missing its address", fileName)
+	}
+
+	// Version 1 only saves the silly name as part of the file name, it is
+	// also what a user renames.
+	base := filepath.Base(fileName)
+	suffix := "_" + monkey.PublicAddress + filepath.Ext(base)
+	if strings.HasSuffix(base, suffix) {
+		monkey.SillyName = strings.TrimSuffix(base, suffix)
+	}
+	return monkey, nil
+}
+
+// parseVersion1 reads a monKey json saved before schema versions, the reply of
+// the monkey server with the address and key added to it.
+func parseVersion1(data []byte) (monkey MonkeyStats, err error) {
+	err = monkey.UnmarshalJSON(data)
+	if err != nil {
+		return monkey, err
+	}
+
+	monkey.PublicAddress = popString(monkey.Additional, "public_address")
+	monkey.PrivateKey = bananoutils.Secret(popString(monkey.Additional, "private_key"))
+	monkey.KeyType = bananoutils.KeyType(popString(monkey.Additional, "key_type"))
+	monkey.Mnemonic = bananoutils.Secret(popString(monkey.Additional, "mnemonic"))
+	monkey.SplitBase = popString(monkey.Additional, "split_base")
+	if _, ok := monkey.Additional["encrypted_secret"]; ok {
+		var encrypted struct {
+			EncryptedSecret *EncryptedSecret `json:"encrypted_secret"`
+		}
+		err = json.Unmarshal(data, &encrypted)
+		if err != nil {
+			return monkey, fmt.Errorf("could not parse encrypted_secret: %w", err)
+		}
+		monkey.EncryptedSecret = encrypted.EncryptedSecret
+		delete(monkey.Additional, "encrypted_secret")
+	}
+	return monkey, nil
+}
+
+func popString(values map[string]interface{}, key string) string {
+	value, _ := values[key].(string)
+	delete(values, key)
+	return value
+}
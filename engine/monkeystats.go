@@ -2,14 +2,19 @@ package engine
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Pallinder/go-randomdata"
+	"github.com/bbedward/crypto/ed25519"
+	"github.com/steampoweredtaco/legion-van/bananoutils"
 	"github.com/ugorji/go/codec"
 )
 
 type Stats struct {
+	// Total is every address tested, by the monkey server or by the address
+	// pattern alone, the same count the odds of a search are for.
 	Total         uint64
 	Found         uint64
 	TotalRequests uint64
@@ -18,16 +23,25 @@ type Stats struct {
 
 type MonkeyBase struct {
 	PublicAddress   string
-	PrivateKey      string
-	BackgroundColor string `json:"background_color"`
-	Glasses         string `json:"glasses"`
-	Hat             string `json:"hat"`
-	Misc            string `json:"misc"`
-	Mouth           string `json:"mouth"`
-	ShirtPants      string `json:"shirt_pants"`
-	Shoes           string `json:"shoes"`
-	Tail            string `json:"tail_accessory"`
-	SillyName       string `json:"silly_name"`
+	PrivateKey      bananoutils.Secret
+	KeyType         bananoutils.KeyType `json:"-"`
+	SplitBase       string              `json:"-"`
+	Mnemonic        bananoutils.Secret  `json:"-"`
+	EncryptedSecret *EncryptedSecret    `json:"-"`
+	BackgroundColor string              `json:"background_color"`
+	Glasses         string              `json:"glasses"`
+	Hat             string              `json:"hat"`
+	Misc            string              `json:"misc"`
+	Mouth           string              `json:"mouth"`
+	ShirtPants      string              `json:"shirt_pants"`
+	Shoes           string              `json:"shoes"`
+	Tail            string              `json:"tail_accessory"`
+	SillyName       string              `json:"silly_name"`
+	// Session, Found and MonkeyServer say where and when the monKey was
+	// found, they are set when it is saved.
+	Session      string    `json:"-"`
+	Found        time.Time `json:"-"`
+	MonkeyServer string    `json:"-"`
 }
 
 type MonkeyStats struct {
@@ -49,13 +63,24 @@ func (monkey *MonkeyStats) UnmarshalJSON(data []byte) (err error) {
 	return
 }
 
+// MarshalJSON saves the monKey as a MonkeyDocument of the current
+// SchemaVersion.
 func (monkey MonkeyStats) MarshalJSON() ([]byte, error) {
-	monkey.Additional["public_address"] = monkey.PublicAddress
-	monkey.Additional["private_key"] = monkey.PrivateKey
-	data := make([]byte, 1000)
-	err := codec.NewEncoderBytes(&data, jsonHandler).Encode(&monkey.Additional)
+	return json.Marshal(NewMonkeyDocument(monkey))
+}
+
+// Keypair derives the keys of the monKey from its saved secret and makes sure
+// they belong to its public address.
+func (monkey MonkeyStats) Keypair() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	if monkey.EncryptedSecret != nil {
+		return nil, nil, errors.New("monKey secret is encrypted")
+	}
+	pub, priv, err := bananoutils.KeypairFromSecret(monkey.KeyType, monkey.PrivateKey.Reveal())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if account := bananoutils.PubKeyToAddress(pub); string(account) != monkey.PublicAddress {
+		return nil, nil, fmt.Errorf("secret derives %s not %s", account, monkey.PublicAddress)
 	}
-	return data, nil
+	return pub, priv, nil
 }
@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	legionImage "github.com/steampoweredtaco/legion-van/image"
+)
+
+// PendingDirName is the directory in the output directory where found monKeys
+// wait for their image.
+const PendingDirName = ".pending"
+
+var imageAttempts = 4
+var imageRetryDelay = time.Second
+var imageTimeout = 30 * time.Second
+
+// SetImageRetry changes how many times the image of a found monKey is asked
+// for before it is left pending, and the wait after the first failure, it
+// doubles after each one.
+func SetImageRetry(attempts int, delay time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	imageAttempts = attempts
+	imageRetryDelay = delay
+}
+
+// SetImageTimeout changes how long a single ask for the image of a found
+// monKey may take, a stalled monkey server counts as a failed attempt.
+func SetImageTimeout(timeout time.Duration) {
+	imageTimeout = timeout
+}
+
+// PendingQueue keeps the json of found monKeys, secret and all, on disk until
+// their image is fetched and every sink has them. A monKey whose image could
+// not be fetched stays in the queue for a later run to finish with
+// FinishPending, so a busy monKey server never costs a seed.
+type PendingQueue struct {
+	dir string
+}
+
+// NewPendingQueue keeps pending monKeys in PendingDirName in dir.
+func NewPendingQueue(dir string) *PendingQueue {
+	return &PendingQueue{dir: filepath.Join(dir, PendingDirName)}
+}
+
+// Add saves the json of found before anything else is done with it.
+func (queue *PendingQueue) Add(found FoundMonkey) error {
+	err := os.MkdirAll(queue.dir, 0700)
+	if err != nil {
+		return fmt.Errorf("could not create pending directory: %w", err)
+	}
+	err = writeFileAtomic(queue.fileName(found), found.JSON, 0600)
+	if err != nil {
+		return fmt.Errorf("could not save pending monKey %s: %w", found.Monkey.SillyName, err)
+	}
+	return nil
+}
+
+// Done removes found from the queue once every sink has it.
+func (queue *PendingQueue) Done(found FoundMonkey) error {
+	err := os.Remove(queue.fileName(found))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove pending monKey %s: %w", found.Monkey.SillyName, err)
+	}
+	return nil
+}
+
+// Load reads every monKey still in the queue, without images.
+func (queue *PendingQueue) Load() ([]FoundMonkey, error) {
+	fileNames, err := filepath.Glob(filepath.Join(queue.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var pending []FoundMonkey
+	for _, fileName := range fileNames {
+		monkey, err := LoadMonkeyFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("could not read pending monKey: %w", err)
+		}
+		pending = append(pending, FoundMonkey{Monkey: monkey, JSON: data})
+	}
+	return pending, nil
+}
+
+func (queue *PendingQueue) fileName(found FoundMonkey) string {
+	return filepath.Join(queue.dir, found.FileName()+".json")
+}
+
+// FinishPending fetches the images of the monKeys left in queue by earlier
+// runs and saves them to sink, the same way OutputMonkeys does.
+func FinishPending(queue *PendingQueue, sink Sink, targetFormat string) error {
+	pending, err := queue.Load()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	log.Infof("Finishing %d monKeys an earlier run could not get images for", len(pending))
+	convert, err := imageConverter(targetFormat)
+	if err != nil {
+		return err
+	}
+	extension := "." + strings.ToLower(targetFormat)
+	var failed int
+	for _, found := range pending {
+		found.ImageExtension = extension
+		err = finishMonkey(queue, sink, convert, found)
+		if err != nil {
+			failed++
+			log.Errorf("could not finish monKey %s at %s: %s", found.Monkey.SillyName, found.Monkey.PublicAddress, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("could not finish %d pending monKeys, they are kept in %s", failed, queue.dir)
+	}
+	return nil
+}
+
+// finishMonkey fetches the image of found, saves it to sink and takes it out
+// of queue.
+func finishMonkey(queue *PendingQueue, sink Sink, convert func(svg io.Reader) ([]byte, error), found FoundMonkey) error {
+	monkeySVG, err := grabMonkeySVG(found.Monkey.PublicAddress)
+	if err != nil {
+		return err
+	}
+	found.Image, err = convert(monkeySVG)
+	if err != nil {
+		return err
+	}
+	err = sink.Write(found)
+	if err != nil {
+		return err
+	}
+	return queue.Done(found)
+}
+
+// grabMonkeySVG fetches the image of address, retrying as SetImageRetry says.
+func grabMonkeySVG(address string) (io.Reader, error) {
+	delay := imageRetryDelay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), imageTimeout)
+		// the image is read before GrabMonkey returns, it is done with ctx
+		monkeySVG, err := bananoutils.GrabMonkey(ctx, bananoutils.Account(address), legionImage.SVGFormat)
+		cancel()
+		if err == nil || attempt >= imageAttempts {
+			return monkeySVG, err
+		}
+		log.Debugf("could not get monKey image, trying again in %s: %s", delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,184 @@
+package engine_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestPendingImages(t *testing.T) {
+	var broken int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&broken) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+	}))
+	defer server.Close()
+	previous := bananoutils.GetMonkeyServer()
+	bananoutils.ChangeMonkeyServer(server.URL)
+	defer bananoutils.ChangeMonkeyServer(previous)
+	engine.SetImageRetry(2, time.Millisecond)
+	defer engine.SetImageRetry(4, time.Second)
+
+	targetDir := t.TempDir()
+	queue := engine.NewPendingQueue(targetDir)
+	monkeys := make(chan engine.MonkeyStats, 1)
+	monkey := engine.MonkeyStats{Additional: map[string]interface{}{"hat": "crown"}}
+	monkey.PublicAddress = testSeedAddress
+	monkey.PrivateKey = bananoutils.Secret(testSeed)
+	monkey.KeyType = bananoutils.KeyTypeSeed
+	monkey.SillyName = "Patience"
+	monkeys <- monkey
+	close(monkeys)
+	sink := engine.NewDirectorySink(targetDir)
+	err := engine.OutputMonkeys(sink, "svg", engine.OutputOptions{Pending: queue}, monkeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without an image only the pending json is on disk, seed and all
+	pendingFile := filepath.Join(targetDir, engine.PendingDirName, "Patience_"+testSeedAddress+".json")
+	saved, err := engine.LoadMonkeyFile(pendingFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.PrivateKey.Reveal() != testSeed || saved.SillyName != "Patience" {
+		t.Fatalf("expected the pending monKey with its seed, got %s %+v", saved.SillyName, saved.MonkeyBase)
+	}
+	if files, _ := filepath.Glob(filepath.Join(targetDir, "Patience*")); len(files) != 0 {
+		t.Fatalf("expected nothing saved before the image, got %v", files)
+	}
+
+	// a later run finishes it once the server is back
+	atomic.StoreInt32(&broken, 0)
+	err = engine.FinishPending(queue, sink, "svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ioutil.ReadDir(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	expected := []string{engine.PendingDirName, "Patience_" + testSeedAddress + ".json", "Patience_" + testSeedAddress + ".svg"}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v without temporary files, got %v", expected, names)
+	}
+	if _, err := os.Stat(pendingFile); !os.IsNotExist(err) {
+		t.Errorf("expected the finished monKey to leave the queue, got %v", err)
+	}
+	finished, err := engine.LoadMonkeyFile(filepath.Join(targetDir, "Patience_"+testSeedAddress+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finished.PrivateKey.Reveal() != testSeed {
+		t.Error("expected the finished monKey to keep its seed")
+	}
+}
+
+func TestStalledImageIsRetried(t *testing.T) {
+	var requests int32
+	stop := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first ask is accepted and never answered
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-stop:
+			}
+			return
+		}
+		fmt.Fprint(w, `<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+	}))
+	defer server.Close()
+	defer close(stop)
+	previous := bananoutils.GetMonkeyServer()
+	bananoutils.ChangeMonkeyServer(server.URL)
+	defer bananoutils.ChangeMonkeyServer(previous)
+	engine.SetImageRetry(2, time.Millisecond)
+	defer engine.SetImageRetry(4, time.Second)
+	engine.SetImageTimeout(50 * time.Millisecond)
+	defer engine.SetImageTimeout(30 * time.Second)
+
+	targetDir := t.TempDir()
+	monkeys := make(chan engine.MonkeyStats, 1)
+	var monkey engine.MonkeyStats
+	monkey.PublicAddress = testSeedAddress
+	monkey.PrivateKey = bananoutils.Secret(testSeed)
+	monkey.KeyType = bananoutils.KeyTypeSeed
+	monkey.SillyName = "Stalled"
+	monkeys <- monkey
+	close(monkeys)
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.OutputMonkeys(engine.NewDirectorySink(targetDir), "svg", engine.OutputOptions{Pending: engine.NewPendingQueue(targetDir)}, monkeys)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("saving waited for a monkey server that never answers")
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "Stalled_"+testSeedAddress+".svg")); err != nil {
+		t.Errorf("expected the second attempt to save the image: %s", err)
+	}
+}
+
+func TestUnqueuedMonkeyIsSavedWithoutImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	previous := bananoutils.GetMonkeyServer()
+	bananoutils.ChangeMonkeyServer(server.URL)
+	defer bananoutils.ChangeMonkeyServer(previous)
+	engine.SetImageRetry(2, time.Millisecond)
+	defer engine.SetImageRetry(4, time.Second)
+
+	// a file in the way of the pending directory, it cannot be created
+	targetDir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(targetDir, engine.PendingDirName), nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monkeys := make(chan engine.MonkeyStats, 1)
+	var monkey engine.MonkeyStats
+	monkey.PublicAddress = testSeedAddress
+	monkey.PrivateKey = bananoutils.Secret(testSeed)
+	monkey.KeyType = bananoutils.KeyTypeSeed
+	monkey.SillyName = "Homeless"
+	monkeys <- monkey
+	close(monkeys)
+	err = engine.OutputMonkeys(engine.NewDirectorySink(targetDir), "svg", engine.OutputOptions{Pending: engine.NewPendingQueue(targetDir)}, monkeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := engine.LoadMonkeyFile(filepath.Join(targetDir, "Homeless_"+testSeedAddress+".json"))
+	if err != nil {
+		t.Fatalf("expected the monKey that could not be kept pending to be saved: %s", err)
+	}
+	if saved.PrivateKey.Reveal() != testSeed {
+		t.Error("expected the saved monKey to keep its seed")
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "Homeless_"+testSeedAddress+".svg")); !os.IsNotExist(err) {
+		t.Errorf("expected no image without one from the monkey server, got %v", err)
+	}
+}
@@ -3,24 +3,24 @@ package engine
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
-	"path"
 	"strings"
 	"time"
 
 	"github.com/Pallinder/go-randomdata"
 	log "github.com/sirupsen/logrus"
 	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/bananoutils/rpc"
 	legionImage "github.com/steampoweredtaco/legion-van/image"
 	"github.com/ugorji/go/codec"
 )
 
-func fetchManyMonkies(ctx context.Context, amount uint) (monKeys []MonkeyStats) {
+func fetchManyMonkies(ctx context.Context, wallets walletsDB) (monKeys []MonkeyStats) {
 	getStatsURL := bananoutils.GetMonkeyDescriptionURI()
-	wallets := generateManyWallets(amount)
 	jsonBody := make(map[string][]string)
 	jsonBody["addresses"] = wallets.getAccounts()
 	jsonReader := wallets.encodeAccountsAsJSON()
@@ -62,20 +62,67 @@ func fetchManyMonkies(ctx context.Context, amount uint) (monKeys []MonkeyStats)
 		return
 	}
 
-	monKeys = make([]MonkeyStats, 0, amount)
+	monKeys = make([]MonkeyStats, 0, len(wallets.getAccounts()))
 	for address, monkey := range results {
+		wallet := wallets.lookupWallet(address)
 		monKeys = append(monKeys, monkey)
 		monKeys[len(monKeys)-1].PublicAddress = address
-		monKeys[len(monKeys)-1].PrivateKey = wallets.lookupWalletSeed(address)
+		monKeys[len(monKeys)-1].PrivateKey = wallet.Secret
+		monKeys[len(monKeys)-1].KeyType = wallet.KeyType
+		monKeys[len(monKeys)-1].SplitBase = wallet.SplitBase
 	}
 	return
 }
+
+// FetchMonkeyStats asks the monkey server for the traits of a single address.
+func FetchMonkeyStats(ctx context.Context, address string) (MonkeyStats, error) {
+	var monkey MonkeyStats
+	body, err := json.Marshal(map[string][]string{"addresses": {address}})
+	if err != nil {
+		return monkey, err
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST", bananoutils.GetMonkeyDescriptionURI(), bytes.NewReader(body))
+	if err != nil {
+		return monkey, fmt.Errorf("could not get monkey stats: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return monkey, fmt.Errorf("could not get monkey stats: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return monkey, fmt.Errorf("non 200 error returned (%d %s)", response.StatusCode, response.Status)
+	}
+
+	results := make(map[string]MonkeyStats)
+	err = codec.NewDecoder(response.Body, jsonHandler).Decode(&results)
+	if err != nil {
+		return monkey, fmt.Errorf("could not unmarshal response: %w", err)
+	}
+	monkey, ok := results[address]
+	if !ok {
+		return monkey, fmt.Errorf("monkey server did not return %s", address)
+	}
+	monkey.PublicAddress = address
+	return monkey, nil
+}
+
+// GenerateAndFilterMonkees tests random wallets for monKeys matching filter
+// until ctx is done. The monKeys of a batch the monkey server already answered
+// are always sent, so the returned channels have to be read until they are
+// closed to not lose any.
 func GenerateAndFilterMonkees(ctx context.Context, monkeysPerRequest uint, filter CmdLineFilter) (monkeyStatsRecieve <-chan MonkeyStats, deltaStatsRecieve <-chan Stats) {
 	monkeyStatsChan := make(chan MonkeyStats, 1000)
 	deltaStatsChan := make(chan Stats, 5)
 	monkeyStatsRecieve = monkeyStatsChan
 	deltaStatsRecieve = deltaStatsChan
 
+	pattern, err := filter.AddressPattern()
+	if err != nil {
+		log.Fatalf("bad address pattern: %s", err)
+	}
+
 	var totalCount uint64
 	var survivorCount uint64
 	raidName := strings.Title(randomdata.Adjective() + " " + randomdata.Noun())
@@ -93,20 +140,26 @@ func GenerateAndFilterMonkees(ctx context.Context, monkeysPerRequest uint, filte
 				break main
 			default:
 			}
-			totalDelta = 0
 			survivorDelta = 0
-			for _, monkey := range fetchManyMonkies(ctx, monkeysPerRequest) {
+			wallets, skipped := generateManyWallets(ctx, monkeysPerRequest, pattern)
+			// keys the pattern left out count as tested, the odds include it
+			totalCount += skipped
+			totalDelta = skipped
+			if len(wallets.getAccounts()) == 0 {
+				if skipped > 0 {
+					deltaStatsChan <- Stats{Total: skipped}
+				}
+				continue
+			}
+			for _, monkey := range fetchManyMonkies(ctx, wallets) {
 				totalCount++
 				totalDelta++
 				if matchFilters(monkey, filter) {
 					survivorCount++
 					survivorDelta++
-					select {
-					case <-ctx.Done():
-
-						break main
-					case monkeyStatsChan <- monkey:
-					}
+					// never dropped once matched, even when ctx is done, its
+					// seed is nowhere else
+					monkeyStatsChan <- monkey
 				}
 			}
 			deltaStatsChan <- Stats{Total: totalDelta, TotalRequests: 1, Found: survivorDelta}
@@ -116,69 +169,281 @@ func GenerateAndFilterMonkees(ctx context.Context, monkeysPerRequest uint, filte
 	return
 }
 
-func OutputMonkeyData(targetDir string, targetFormat string, monkeyDataChan <-chan MonkeyStats) {
+// how long a partial batch of input wallets waits for more before it is tested.
+const inputFlushInterval = time.Second
 
-	var convert func(svg io.Reader) (io.Reader, error)
-	extension := "." + strings.ToLower(targetFormat)
+// how many times a batch of input wallets is tried before giving up on it.
+const inputFetchAttempts = 3
 
-	switch format := strings.ToUpper(targetFormat); format {
-	case "PNG":
-		convert = func(svg io.Reader) (io.Reader, error) {
-			dataBytes, err := io.ReadAll(svg)
-			if err != nil {
-				return nil, err
+// FilterMonkees is GenerateAndFilterMonkees for wallets that already exist,
+// each one read from wallets is tested instead of random ones. Wallets the
+// address pattern of filter leaves out are counted but never asked for. Batches are
+// sent when full or when the input goes quiet so slow inputs are not held back.
+// The returned channels close once wallets is closed and drained, or ctx is
+// done and the matches already found are sent.
+func FilterMonkees(ctx context.Context, wallets <-chan Wallet, monkeysPerRequest uint, filter CmdLineFilter) (monkeyStatsRecieve <-chan MonkeyStats, deltaStatsRecieve <-chan Stats) {
+	monkeyStatsChan := make(chan MonkeyStats, 1000)
+	deltaStatsChan := make(chan Stats, 5)
+	monkeyStatsRecieve = monkeyStatsChan
+	deltaStatsRecieve = deltaStatsChan
+
+	pattern, err := filter.AddressPattern()
+	if err != nil {
+		log.Fatalf("bad address pattern: %s", err)
+	}
+
+	go func() {
+		defer close(monkeyStatsChan)
+		defer close(deltaStatsChan)
+		batch := make([]Wallet, 0, monkeysPerRequest)
+		var skipped uint64
+		flush := time.NewTicker(inputFlushInterval)
+		defer flush.Stop()
+
+		test := func() bool {
+			if len(batch) == 0 {
+				if skipped > 0 {
+					deltaStatsChan <- Stats{Total: skipped}
+					skipped = 0
+				}
+				return true
 			}
-			data, err := legionImage.ConvertSvgToBinary(dataBytes, legionImage.PNGFormat, 250)
-			if err != nil {
-				return nil, err
+			var monkeys []MonkeyStats
+			// unlike random wallets a lost batch here is never tested, so retry it
+			for attempt := 0; attempt < inputFetchAttempts && len(monkeys) == 0; attempt++ {
+				monkeys = fetchManyMonkies(ctx, newWalletsDB(batch))
+				if ctx.Err() != nil {
+					return false
+				}
 			}
-
-			return bytes.NewReader(data), nil
+			if len(monkeys) == 0 {
+				log.Errorf("gave up testing %d wallets from the input, the monkey server did not answer", len(batch))
+			}
+			stats := Stats{Total: uint64(len(monkeys)) + skipped, TotalRequests: 1}
+			skipped = 0
+			for _, monkey := range monkeys {
+				if matchFilters(monkey, filter) {
+					stats.Found++
+					monkeyStatsChan <- monkey
+				}
+			}
+			deltaStatsChan <- stats
+			batch = batch[:0]
+			return true
 		}
-	case "SVG":
-		convert = func(svg io.Reader) (io.Reader, error) {
-			return svg, nil
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case wallet, ok := <-wallets:
+				if !ok {
+					test()
+					return
+				}
+				if !pattern.Match(wallet.Address) {
+					skipped++
+					continue
+				}
+				batch = append(batch, wallet)
+				if uint(len(batch)) >= monkeysPerRequest && !test() {
+					return
+				}
+			case <-flush.C:
+				if !test() {
+					return
+				}
+			}
 		}
-	default:
-		log.Fatalf("cannot convert to format %s.", targetFormat)
+	}()
+	return
+}
+
+// OutputOptions changes what is saved for each found monKey.
+type OutputOptions struct {
+	// Mnemonic adds the BIP39 words of wallet seeds to the saved json.
+	Mnemonic bool
+	// Encrypter when set replaces the private key and mnemonic in the saved
+	// json with an encrypted version, the plaintext is never written.
+	Encrypter *SecretEncrypter
+	// Node when set is asked if every found address is still unopened, for at
+	// most NodeTimeout or defaultNodeTimeout when it is zero.
+	Node        *rpc.Client
+	NodeTimeout time.Duration
+	// Insecure refuses to save anything, for runs with keys anyone can make
+	// again.
+	Insecure bool
+	// Pending when set keeps the json of every monKey until it is saved to
+	// the sink, a monKey whose image could not be fetched is left for
+	// FinishPending.
+	Pending *PendingQueue
+	// Session is saved in the json of monKeys found in this run.
+	Session string
+}
+
+// OutputMonkeyData saves found monKeys as files in targetDir, see
+// OutputMonkeys. Their json is kept pending in targetDir until the image is
+// saved too, unless options already has a queue.
+func OutputMonkeyData(targetDir string, targetFormat string, options OutputOptions, monkeyDataChan <-chan MonkeyStats) {
+	if options.Pending == nil {
+		options.Pending = NewPendingQueue(targetDir)
+	}
+	err := OutputMonkeys(NewDirectorySink(targetDir), targetFormat, options, monkeyDataChan)
+	if err != nil {
+		log.Error(err)
 	}
+}
 
-	for {
-		monkey, ok := <-monkeyDataChan
-		if !ok {
-			return
+// OutputMonkeys prepares every monKey from monkeyDataChan as options say and
+// writes it to sink, until the channel is closed. The sink has to be open
+// already. A monKey that cannot be saved is logged and the rest are still
+// saved, the returned error only counts them.
+func OutputMonkeys(sink Sink, targetFormat string, options OutputOptions, monkeyDataChan <-chan MonkeyStats) error {
+	convert, err := imageConverter(targetFormat)
+	if err != nil {
+		return err
+	}
+	extension := "." + strings.ToLower(targetFormat)
+
+	var failed int
+	for monkey := range monkeyDataChan {
+		if options.Insecure {
+			log.Warnf("not saving monKey %s at %s, its key is insecure and deterministic", monkey.SillyName, monkey.PublicAddress)
+			continue
 		}
-		monkeySVG, err := bananoutils.GrabMonkey(context.Background(), bananoutils.Account(monkey.PublicAddress), legionImage.SVGFormat)
+		found, err := prepareMonkey(monkey, options)
 		if err != nil {
-			log.Warnf("lost a monkey %s", err)
+			failed++
+			log.Errorf("could not save monKey %s at %s: %s", monkey.SillyName, monkey.PublicAddress, err)
 			continue
 		}
+		found.ImageExtension = extension
+		// the secret is on disk before anything can go wrong with the image
+		queued := false
+		if options.Pending != nil {
+			err = options.Pending.Add(found)
+			if err != nil {
+				log.Errorf("could not keep monKey %s at %s safe before getting its image: %s", monkey.SillyName, monkey.PublicAddress, err)
+			} else {
+				queued = true
+			}
+		}
 
-		targetName := monkey.SillyName + "_" + monkey.PublicAddress
-		targetJson := path.Join(targetDir, targetName) + ".json"
-
-		var targetImgFile string = path.Join(targetDir, targetName) + extension
+		if options.Node != nil {
+			checkUnopened(options.Node, options.NodeTimeout, monkey)
+		}
 
-		jsonData, err := json.MarshalIndent(monkey, "", "  ")
-		if err != nil {
-			log.Fatalf("couldn't marshal monKey %s", monkey.SillyName)
+		monkeySVG, err := grabMonkeySVG(monkey.PublicAddress)
+		if err == nil {
+			found.Image, err = convert(monkeySVG)
+		} else if queued {
+			log.Warnf("could not get the image of monKey %s at %s, a later run will finish it: %s", monkey.SillyName, monkey.PublicAddress, err)
+			continue
 		}
-		err = ioutil.WriteFile(targetJson, jsonData, 0600)
-		if err != nil {
-			log.Fatalf("could now write monkey, sad %s: %s", monkey.SillyName, err)
+		if err != nil && !queued {
+			// nothing else has the secret, the sinks get it without the image
+			log.Warnf("could not get the image of monKey %s at %s, saving it without one, verify --fix can get it later: %s", monkey.SillyName, monkey.PublicAddress, err)
+			found.Image = nil
+			err = nil
+		}
+		if err == nil {
+			err = sink.Write(found)
+		}
+		if err == nil && queued {
+			err = options.Pending.Done(found)
 		}
-		monkeyConverted, err := convert(monkeySVG)
 		if err != nil {
-			log.Fatalf("could not convert monkey image, sad monkey %s: %s", monkey.SillyName, err)
+			failed++
+			log.Errorf("could not save monKey %s at %s: %s", monkey.SillyName, monkey.PublicAddress, err)
 		}
-		monkeyData, err := io.ReadAll(monkeyConverted)
+	}
+	if failed > 0 {
+		return fmt.Errorf("could not save %d monKeys, see the errors logged for them", failed)
+	}
+	return nil
+}
+
+// imageConverter returns a function that converts a monKey svg to
+// targetFormat.
+func imageConverter(targetFormat string) (func(svg io.Reader) ([]byte, error), error) {
+	switch format := strings.ToUpper(targetFormat); format {
+	case "PNG":
+		return func(svg io.Reader) ([]byte, error) {
+			dataBytes, err := io.ReadAll(svg)
+			if err != nil {
+				return nil, err
+			}
+			return legionImage.ConvertSvgToBinary(dataBytes, legionImage.PNGFormat, 250)
+		}, nil
+	case "SVG":
+		return io.ReadAll, nil
+	}
+	return nil, fmt.Errorf("cannot convert to format %s", targetFormat)
+}
+
+// prepareMonkey adds the mnemonic or encrypts the secret of monkey as options
+// say and makes the json every sink saves.
+func prepareMonkey(monkey MonkeyStats, options OutputOptions) (FoundMonkey, error) {
+	var err error
+	// a monKey loaded from a file keeps where and when it was found
+	if monkey.Found.IsZero() {
+		monkey.Session = options.Session
+		monkey.Found = time.Now()
+		monkey.MonkeyServer = bananoutils.GetMonkeyServer()
+	}
+	if options.Mnemonic && monkey.KeyType == bananoutils.KeyTypeSeed {
+		monkey.Mnemonic, err = seedMnemonic(monkey.PrivateKey)
 		if err != nil {
-			log.Fatalf("could not write monkey image, sad monkey %s: %s", monkey.SillyName, err)
+			return FoundMonkey{}, fmt.Errorf("couldn't make mnemonic: %w", err)
 		}
-		err = ioutil.WriteFile(targetImgFile, monkeyData, 0600)
+	}
+
+	if options.Encrypter != nil && monkey.KeyType != bananoutils.KeyTypeNone {
+		monkey.EncryptedSecret, err = options.Encrypter.Encrypt(monkey)
 		if err != nil {
-			log.Fatalf("could now write monkey, sad monkey %s: %s", monkey.SillyName, err)
+			// never fall back to saving the plaintext
+			return FoundMonkey{}, fmt.Errorf("couldn't encrypt: %w", err)
 		}
+		monkey.PrivateKey = ""
+		monkey.Mnemonic = ""
+	}
+
+	jsonData, err := json.MarshalIndent(monkey, "", "  ")
+	if err != nil {
+		return FoundMonkey{}, fmt.Errorf("couldn't marshal: %w", err)
+	}
+	return FoundMonkey{Monkey: monkey, JSON: jsonData}, nil
+}
+
+// defaultNodeTimeout is how long a found monKey waits for the node to say if
+// its address is unopened.
+const defaultNodeTimeout = 5 * time.Second
+
+// checkUnopened warns loudly about found addresses that already have blocks,
+// a fresh key colliding with a used account means the keys are not random.
+// The monKey is still saved, it is never worth losing a key over, and a node
+// that does not answer in time only costs the check.
+func checkUnopened(node *rpc.Client, timeout time.Duration, monkey MonkeyStats) {
+	if timeout <= 0 {
+		timeout = defaultNodeTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	unopened, err := node.IsUnopened(ctx, bananoutils.Account(monkey.PublicAddress))
+	if err != nil {
+		log.Warnf("could not check monKey %s is unopened: %s", monkey.SillyName, err)
+		return
+	}
+	if !unopened {
+		log.Errorf("monKey %s at %s is already opened on the node, someone else has used this key, check your entropy source!", monkey.SillyName, monkey.PublicAddress)
+	}
+}
 
+func seedMnemonic(seed bananoutils.Secret) (bananoutils.Secret, error) {
+	seedBytes, err := hex.DecodeString(seed.Reveal())
+	if err != nil {
+		return "", err
 	}
+	mnemonic, err := bananoutils.SeedToMnemonic(seedBytes)
+	return bananoutils.Secret(mnemonic), err
 }
@@ -0,0 +1,116 @@
+package engine_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/bananoutils/rpc"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+// TestShutdownDrainsMatches stops a search while a whole answered batch of
+// matches does not fit the channel, every one of them still has to come out.
+func TestShutdownDrainsMatches(t *testing.T) {
+	const batchSize = 1500
+	var mu sync.Mutex
+	matched := make(map[string]bool)
+	newStubMonkeyServer(t, func(address string) map[string]string {
+		mu.Lock()
+		matched[address] = true
+		mu.Unlock()
+		return map[string]string{"hat": "crown"}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	monkeys, stats := engine.GenerateAndFilterMonkees(ctx, batchSize, engine.CmdLineFilter{Hat: []string{"crown"}})
+	// nobody reads until the channel is full and the search is blocked on the
+	// rest of the first batch
+	deadline := time.Now().Add(10 * time.Second)
+	for len(monkeys) < cap(monkeys) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the channel to fill up, it has %d monKeys", len(monkeys))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	var found uint64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for delta := range stats {
+			found += delta.Found
+		}
+	}()
+	received := make(map[string]bool)
+	for monkey := range monkeys {
+		received[monkey.PublicAddress] = true
+	}
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	// a second batch would have asked for more random addresses
+	if len(matched) != batchSize {
+		t.Errorf("expected the search to stop after the first batch, it asked for %d addresses", len(matched))
+	}
+	if len(received) != batchSize {
+		t.Fatalf("expected all %d matches of the batch, got %d of %d", batchSize, len(received), len(matched))
+	}
+	for address := range matched {
+		if !received[address] {
+			t.Errorf("lost %s", address)
+		}
+	}
+	if found != batchSize {
+		t.Errorf("expected the stats to count %d found, got %d", batchSize, found)
+	}
+}
+
+func TestSilentNodeDoesNotBlockSaving(t *testing.T) {
+	newStubMonkeyServer(t, nil)
+	stop := make(chan struct{})
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// accepts the request and never answers
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+	defer node.Close()
+	defer close(stop)
+
+	targetDir := t.TempDir()
+	monkeys := make(chan engine.MonkeyStats, 1)
+	var monkey engine.MonkeyStats
+	monkey.PublicAddress = testSeedAddress
+	monkey.PrivateKey = bananoutils.Secret(testSeed)
+	monkey.KeyType = bananoutils.KeyTypeSeed
+	monkey.SillyName = "Patient"
+	monkeys <- monkey
+	close(monkeys)
+	options := engine.OutputOptions{Node: rpc.NewClient(node.URL, nil), NodeTimeout: 50 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.OutputMonkeys(engine.NewDirectorySink(targetDir), "svg", options, monkeys)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("saving waited for a node that never answers")
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "Patient_"+testSeedAddress+".svg")); err != nil {
+		t.Errorf("expected the monKey to be saved without the check: %s", err)
+	}
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const redactedHex = "[REDACTED]"
+
+// Seeds and private keys are 64 hex characters, anything that long is
+// scrubbed even if it is only a block hash, better safe than sorry.
+var secretHexPattern = regexp.MustCompile(`[0-9A-Fa-f]{64,}`)
+
+// RedactHook is a logrus hook that scrubs anything shaped like a seed or
+// private key from log entries before they are written. Add it before any
+// other hook so they only ever see the scrubbed entry.
+type RedactHook struct{}
+
+func (RedactHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (RedactHook) Fire(entry *log.Entry) error {
+	entry.Message = RedactHex(entry.Message)
+	for key, value := range entry.Data {
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case error:
+			text = v.Error()
+		case fmt.Stringer:
+			text = v.String()
+		default:
+			text = fmt.Sprintf("%v", v)
+		}
+		if scrubbed := RedactHex(text); scrubbed != text {
+			entry.Data[key] = scrubbed
+		}
+	}
+	return nil
+}
+
+// RedactHex replaces every run of 64 or more hex characters in s.
+func RedactHex(s string) string {
+	return secretHexPattern.ReplaceAllString(s, redactedHex)
+}
@@ -0,0 +1,112 @@
+package engine_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestSecretFormatting(t *testing.T) {
+	secret := bananoutils.Secret("deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	monkey := engine.MonkeyStats{}
+	monkey.PrivateKey = secret
+	for _, format := range []string{"%s", "%v", "%+v", "%#v", "%x", "%X", "%q"} {
+		for _, value := range []interface{}{secret, monkey, &monkey} {
+			if out := fmt.Sprintf(format, value); strings.Contains(strings.ToLower(out), "deadbeef") {
+				t.Errorf("%s leaked the secret: %s", format, out)
+			}
+		}
+	}
+	text, err := secret.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(text), "deadbeef") {
+		t.Error("MarshalText leaked the secret")
+	}
+}
+
+func TestNoSeedsInLogs(t *testing.T) {
+	newStubMonkeyServer(t, nil)
+	targetDir := t.TempDir()
+
+	var logOutput bytes.Buffer
+	logger := log.StandardLogger()
+	previousOut, previousLevel, previousHooks := logger.Out, logger.GetLevel(), logger.Hooks
+	logger.SetOutput(&logOutput)
+	logger.SetLevel(log.DebugLevel)
+	logger.ReplaceHooks(make(log.LevelHooks))
+	logger.AddHook(engine.RedactHook{})
+	defer func() {
+		logger.SetOutput(previousOut)
+		logger.SetLevel(previousLevel)
+		logger.ReplaceHooks(previousHooks)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	monkeys, stats := engine.GenerateAndFilterMonkees(ctx, 20, engine.CmdLineFilter{Hat: []string{"crown"}})
+
+	writeChan := make(chan engine.MonkeyStats, 100)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		engine.OutputMonkeyData(targetDir, "svg", engine.OutputOptions{Mnemonic: true}, writeChan)
+	}()
+	go func() {
+		batches := 0
+		for range stats {
+			batches++
+			if batches == 3 {
+				cancel()
+			}
+		}
+	}()
+	for monkey := range monkeys {
+		// the careless log lines this is all about
+		log.Infof("found %v", monkey)
+		log.Debugf("found %+v %#v %x %q", monkey, monkey, monkey.PrivateKey, monkey.PrivateKey)
+		log.WithField("seed", monkey.PrivateKey.Reveal()).Warn("found a monKey")
+		log.Errorf("revealed %s", monkey.PrivateKey.Reveal())
+		writeChan <- monkey
+	}
+	close(writeChan)
+	wg.Wait()
+
+	files, err := filepath.Glob(filepath.Join(targetDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("expected monKeys to be saved")
+	}
+	logs := strings.ToLower(logOutput.String())
+	for _, fileName := range files {
+		monkey, err := engine.LoadMonkeyFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seed := strings.ToLower(monkey.PrivateKey.Reveal())
+		if len(seed) != 64 {
+			t.Fatalf("expected a seed to be saved in %s", fileName)
+		}
+		if strings.Contains(logs, seed) {
+			t.Errorf("seed of %s is in the logs", filepath.Base(fileName))
+		}
+		if strings.Contains(logs, strings.Join(strings.Fields(monkey.Mnemonic.Reveal())[:4], " ")) {
+			t.Errorf("mnemonic of %s is in the logs", filepath.Base(fileName))
+		}
+	}
+	if !strings.Contains(logs, "[redacted]") {
+		t.Error("expected redacted secrets in the logs")
+	}
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FoundMonkey is a found monKey ready to be saved, its secret is already
+// encrypted when that was asked for.
+type FoundMonkey struct {
+	Monkey MonkeyStats
+	// JSON is the saved json of the monKey, the same for every sink.
+	JSON []byte
+	// Image is the monKey in the format of ImageExtension, like ".png". It is
+	// nil when the image could not be fetched and the monKey could not be
+	// kept pending either, sinks then save it without one.
+	Image          []byte
+	ImageExtension string
+}
+
+// FileName is the name found monKey files get without an extension, the silly
+// name followed by the address.
+func (found FoundMonkey) FileName() string {
+	return found.Monkey.SillyName + "_" + found.Monkey.PublicAddress
+}
+
+// Sink is somewhere found monKeys are saved. Open is called once before the
+// first Write with the id of the search session and Close once after the
+// last. Write is called from many goroutines at once.
+type Sink interface {
+	Open(session string) error
+	Write(found FoundMonkey) error
+	Close() error
+}
+
+// NewSessionID names a search session, sortable by the time it started.
+func NewSessionID() string {
+	suffix := make([]byte, 4)
+	_, err := rand.Read(suffix)
+	if err != nil {
+		panic(err)
+	}
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// SinkError is every error of a fan-out sink, errors.Is and errors.As look at
+// each of them.
+type SinkError []error
+
+func (errs SinkError) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", errs[0], len(errs)-1)
+}
+
+func (errs SinkError) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (errs SinkError) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type fanOut []Sink
+
+// NewFanOut is a sink that saves to every one of sinks. A failing sink does
+// not stop the others from getting the monKey, its error is returned after
+// all of them were tried.
+func NewFanOut(sinks ...Sink) Sink {
+	return fanOut(sinks)
+}
+
+func (sinks fanOut) Open(session string) error {
+	for i, sink := range sinks {
+		err := sink.Open(session)
+		if err != nil {
+			// close the ones already opened, the search will not start
+			for _, opened := range sinks[:i] {
+				opened.Close()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (sinks fanOut) Write(found FoundMonkey) error {
+	var errs SinkError
+	for _, sink := range sinks {
+		err := sink.Write(found)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+func (sinks fanOut) Close() error {
+	var errs SinkError
+	for _, sink := range sinks {
+		err := sink.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package engine_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func testFoundMonkey(name string) engine.FoundMonkey {
+	var monkey engine.MonkeyStats
+	monkey.SillyName = name
+	monkey.PublicAddress = testSeedAddress
+	return engine.FoundMonkey{
+		Monkey:         monkey,
+		JSON:           []byte("{\n  \"public_address\": \"" + testSeedAddress + "\"\n}"),
+		Image:          []byte("<svg></svg>"),
+		ImageExtension: ".svg",
+	}
+}
+
+type failingSink struct {
+	writes int
+}
+
+var errSinkBroken = errors.New("sink is broken")
+
+func (sink *failingSink) Open(session string) error { return nil }
+func (sink *failingSink) Write(found engine.FoundMonkey) error {
+	sink.writes++
+	return errSinkBroken
+}
+func (sink *failingSink) Close() error { return nil }
+
+func TestFanOut(t *testing.T) {
+	var lines bytes.Buffer
+	broken := &failingSink{}
+	sink := engine.NewFanOut(broken, engine.NewJSONLinesSink(&lines))
+	err := sink.Open(engine.NewSessionID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Write(testFoundMonkey("Fanned"))
+	if !errors.Is(err, errSinkBroken) {
+		t.Errorf("expected the broken sink error, got %v", err)
+	}
+	if broken.writes != 1 {
+		t.Errorf("expected 1 write to the broken sink, got %d", broken.writes)
+	}
+	if got := lines.String(); got != "{\"public_address\":\""+testSeedAddress+"\"}\n" {
+		t.Errorf("the other sink still has to get the monKey as one line, got %q", got)
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestArchiveSink(t *testing.T) {
+	dir := t.TempDir()
+	session := engine.NewSessionID()
+	sink := engine.NewArchiveSink(dir)
+	err := sink.Open(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"First", "Second"} {
+		err = sink.Write(testFoundMonkey(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a second run with the same session must not overwrite the archive
+	if err := engine.NewArchiveSink(dir).Open(session); err == nil {
+		t.Error("expected opening an existing archive to fail")
+	}
+
+	file, err := os.Open(filepath.Join(dir, engine.ArchiveName(session)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if stat, _ := file.Stat(); runtime.GOOS != "windows" && stat.Mode().Perm() != 0600 {
+		t.Errorf("expected the archive to be 0600, got %v", stat.Mode().Perm())
+	}
+	var names []string
+	archive := tar.NewReader(file)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+		if strings.HasSuffix(header.Name, ".json") {
+			var saved map[string]string
+			err = json.NewDecoder(archive).Decode(&saved)
+			if err != nil || saved["public_address"] != testSeedAddress {
+				t.Errorf("bad json in %s: %v %v", header.Name, saved, err)
+			}
+		}
+	}
+	expected := []string{
+		"First_" + testSeedAddress + ".json", "First_" + testSeedAddress + ".svg",
+		"Second_" + testSeedAddress + ".json", "Second_" + testSeedAddress + ".svg",
+	}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v in the archive, got %v", expected, names)
+	}
+}
+
+func TestExecSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook command is written for sh")
+	}
+	dir := t.TempDir()
+	output := filepath.Join(dir, "hook.txt")
+	sink := engine.NewExecSink(`echo "$LEGION_VAN_SESSION $LEGION_VAN_SILLY_NAME $LEGION_VAN_ADDRESS" > "`+output+`" && cat "$LEGION_VAN_IMAGE" >> "`+output+`" && cat >> "`+output+`"`,
+		engine.ExecOptions{Timeout: time.Minute, Concurrency: 1})
+	err := sink.Open("session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := testFoundMonkey("Hooked")
+	found.JSON = []byte(`{"schema_version":2,"seed":"secret","address":"` + testSeedAddress + `"}`)
+	err = sink.Write(found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// close waits for the command
+	err = sink.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "session-1 Hooked " + testSeedAddress + "\n" + string(found.Image) + string(found.JSON)
+	if string(data) != expected {
+		t.Errorf("expected the hook to get\n%s\ngot\n%s", expected, data)
+	}
+
+	sink = engine.NewExecSink(`cat > "`+output+`"`, engine.ExecOptions{NoSecret: true})
+	sink.Write(found)
+	sink.Close()
+	data, err = ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) || !bytes.Contains(data, []byte(testSeedAddress)) {
+		t.Errorf("expected the json without the seed, got %s", data)
+	}
+}
+
+func TestExecSinkFailures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook command is written for sh")
+	}
+	// failing and slow commands are logged, saving goes on
+	sink := engine.NewExecSink("exit 3", engine.ExecOptions{})
+	if err := sink.Write(testFoundMonkey("Failed")); err != nil {
+		t.Errorf("a failing command must not fail the write, got %s", err)
+	}
+	sink.Close()
+
+	started := time.Now()
+	sink = engine.NewExecSink("sleep 10", engine.ExecOptions{Timeout: 100 * time.Millisecond, Concurrency: 2})
+	for i := 0; i < 4; i++ {
+		sink.Write(testFoundMonkey("Slow"))
+	}
+	sink.Close()
+	// two at a time, each killed after the timeout
+	if took := time.Since(started); took > 5*time.Second || took < 200*time.Millisecond {
+		t.Errorf("expected the commands to time out two at a time, took %s", took)
+	}
+}
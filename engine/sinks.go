@@ -0,0 +1,314 @@
+package engine
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type directorySink struct {
+	dir string
+}
+
+// NewDirectorySink saves the json and image of every monKey as two files in
+// dir, named after the silly name and address of the monKey.
+func NewDirectorySink(dir string) Sink {
+	return &directorySink{dir: dir}
+}
+
+func (sink *directorySink) Open(session string) error {
+	err := os.MkdirAll(sink.dir, 0700)
+	if err != nil {
+		return fmt.Errorf("could not create directory: %w", err)
+	}
+	return nil
+}
+
+func (sink *directorySink) Write(found FoundMonkey) error {
+	target := path.Join(sink.dir, found.FileName())
+	// the json with the secret goes first, each file appears whole or not at
+	// all
+	err := writeFileAtomic(target+".json", found.JSON, 0600)
+	if err != nil {
+		return fmt.Errorf("could not write monKey %s: %w", found.Monkey.SillyName, err)
+	}
+	if found.Image == nil {
+		return nil
+	}
+	err = writeFileAtomic(target+found.ImageExtension, found.Image, 0600)
+	if err != nil {
+		return fmt.Errorf("could not write monKey image %s: %w", found.Monkey.SillyName, err)
+	}
+	return nil
+}
+
+func (sink *directorySink) Close() error {
+	return nil
+}
+
+type jsonLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesSink writes the json of every monKey as a single line to w,
+// without the image.
+func NewJSONLinesSink(w io.Writer) Sink {
+	return &jsonLinesSink{w: w}
+}
+
+func (sink *jsonLinesSink) Open(session string) error {
+	return nil
+}
+
+func (sink *jsonLinesSink) Write(found FoundMonkey) error {
+	var line bytes.Buffer
+	err := json.Compact(&line, found.JSON)
+	if err != nil {
+		return fmt.Errorf("could not compact monKey %s: %w", found.Monkey.SillyName, err)
+	}
+	line.WriteByte('\n')
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	_, err = sink.w.Write(line.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write monKey %s: %w", found.Monkey.SillyName, err)
+	}
+	return nil
+}
+
+func (sink *jsonLinesSink) Close() error {
+	return nil
+}
+
+type archiveSink struct {
+	dir string
+
+	mu      sync.Mutex
+	file    *os.File
+	archive *tar.Writer
+}
+
+// NewArchiveSink saves every monKey of a session in a single tar file in dir,
+// named after the session. Each monKey is flushed to disk as it is found, so
+// an interrupted search still leaves a readable archive.
+func NewArchiveSink(dir string) Sink {
+	return &archiveSink{dir: dir}
+}
+
+// ArchiveName is the file name of the archive of session.
+func ArchiveName(session string) string {
+	return "legion-van-" + session + ".tar"
+}
+
+func (sink *archiveSink) Open(session string) error {
+	err := os.MkdirAll(sink.dir, 0700)
+	if err != nil {
+		return fmt.Errorf("could not create directory: %w", err)
+	}
+	sink.file, err = os.OpenFile(path.Join(sink.dir, ArchiveName(session)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create archive: %w", err)
+	}
+	sink.archive = tar.NewWriter(sink.file)
+	return nil
+}
+
+func (sink *archiveSink) Write(found FoundMonkey) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	now := time.Now()
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{found.FileName() + ".json", found.JSON},
+		{found.FileName() + found.ImageExtension, found.Image},
+	} {
+		if entry.data == nil {
+			continue
+		}
+		err := sink.archive.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entry.name,
+			Mode:     0600,
+			Size:     int64(len(entry.data)),
+			ModTime:  now,
+		})
+		if err == nil {
+			_, err = sink.archive.Write(entry.data)
+		}
+		if err != nil {
+			return fmt.Errorf("could not archive monKey %s: %w", found.Monkey.SillyName, err)
+		}
+	}
+	err := sink.archive.Flush()
+	if err == nil {
+		err = sink.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("could not archive monKey %s: %w", found.Monkey.SillyName, err)
+	}
+	return nil
+}
+
+func (sink *archiveSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.file == nil {
+		return nil
+	}
+	err := sink.archive.Close()
+	closeErr := sink.file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not finish archive: %w", err)
+	}
+	return nil
+}
+
+// ExecOptions changes how the command of an exec sink is run.
+type ExecOptions struct {
+	// Timeout is how long the command may run for a single monKey.
+	Timeout time.Duration
+	// Concurrency is how many commands may run at once, more wait their turn.
+	Concurrency int
+	// NoSecret leaves the private key and mnemonic out of the json.
+	NoSecret bool
+	// ImageDir is where the directory sink saves the images, when empty the
+	// image is put in a temporary file for the command.
+	ImageDir string
+}
+
+type execSink struct {
+	command string
+	options ExecOptions
+	session string
+	slots   chan struct{}
+	running sync.WaitGroup
+}
+
+// NewExecSink runs command with the shell for every monKey, with its json on
+// stdin and LEGION_VAN_SESSION, LEGION_VAN_ADDRESS, LEGION_VAN_SILLY_NAME,
+// LEGION_VAN_KEY_TYPE and LEGION_VAN_IMAGE, the path of the image or empty
+// when it has none, set. The json holds the secret unless it is encrypted or
+// options.NoSecret is set, only run commands you trust with your keys.
+//
+// Commands run in the background and a failing one is only logged, Write
+// never fails and Close waits for the commands still running.
+func NewExecSink(command string, options ExecOptions) Sink {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	return &execSink{command: command, options: options, slots: make(chan struct{}, options.Concurrency)}
+}
+
+func (sink *execSink) Open(session string) error {
+	sink.session = session
+	return nil
+}
+
+func (sink *execSink) Write(found FoundMonkey) error {
+	sink.running.Add(1)
+	go func() {
+		defer sink.running.Done()
+		sink.slots <- struct{}{}
+		defer func() { <-sink.slots }()
+		err := sink.run(found)
+		if err != nil {
+			log.Warnf("on found command failed for monKey %s at %s: %s", found.Monkey.SillyName, found.Monkey.PublicAddress, err)
+		}
+	}()
+	return nil
+}
+
+func (sink *execSink) run(found FoundMonkey) error {
+	input := found.JSON
+	if sink.options.NoSecret {
+		var err error
+		input, err = withoutSecret(found.JSON)
+		if err != nil {
+			return err
+		}
+	}
+
+	image := path.Join(sink.options.ImageDir, found.FileName()+found.ImageExtension)
+	if found.Image == nil {
+		image = ""
+	} else if sink.options.ImageDir == "" {
+		file, err := ioutil.TempFile("", "legion-van-*"+found.ImageExtension)
+		if err != nil {
+			return fmt.Errorf("could not save image for the command: %w", err)
+		}
+		image = file.Name()
+		defer os.Remove(image)
+		_, err = file.Write(found.Image)
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("could not save image for the command: %w", err)
+		}
+	}
+
+	ctx := context.Background()
+	if sink.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sink.options.Timeout)
+		defer cancel()
+	}
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, sink.command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"LEGION_VAN_SESSION="+sink.session,
+		"LEGION_VAN_ADDRESS="+found.Monkey.PublicAddress,
+		"LEGION_VAN_SILLY_NAME="+found.Monkey.SillyName,
+		"LEGION_VAN_KEY_TYPE="+string(found.Monkey.KeyType),
+		"LEGION_VAN_IMAGE="+image,
+	)
+	// the output is not kept, the command may have been given the secret and
+	// print it
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", sink.options.Timeout)
+	}
+	return err
+}
+
+// withoutSecret removes the plaintext secrets from a saved monKey json, an
+// encrypted secret is left in.
+func withoutSecret(data []byte) ([]byte, error) {
+	var saved map[string]interface{}
+	err := json.Unmarshal(data, &saved)
+	if err != nil {
+		return nil, fmt.Errorf("could not read monKey json: %w", err)
+	}
+	delete(saved, "seed")
+	delete(saved, "private_key")
+	delete(saved, "mnemonic")
+	return json.MarshalIndent(saved, "", "  ")
+}
+
+func (sink *execSink) Close() error {
+	sink.running.Wait()
+	return nil
+}
@@ -0,0 +1,57 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// newStubMonkeyServer starts a stand in for the monKey api. Every monKey it
+// describes gets the traits returned by traits, or a crown and cigar when
+// traits is nil, and images are a tiny svg naming the address.
+func newStubMonkeyServer(t *testing.T, traits func(address string) map[string]string) *httptest.Server {
+	t.Helper()
+	if traits == nil {
+		traits = func(string) map[string]string {
+			return map[string]string{"hat": "crown", "mouth": "cigar"}
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/monkey/dtl", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Addresses []string `json:"addresses"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		results := make(map[string]map[string]string, len(request.Addresses))
+		for _, address := range request.Addresses {
+			results[address] = traits(address)
+		}
+		json.NewEncoder(w).Encode(results)
+	})
+	mux.HandleFunc("/api/v1/monkey/", func(w http.ResponseWriter, r *http.Request) {
+		address := strings.TrimPrefix(r.URL.Path, "/api/v1/monkey/")
+		if !bananoutils.ValidateAddress(bananoutils.Account(address)) {
+			http.Error(w, "bad address", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg"><title>%s</title></svg>`, address)
+	})
+	server := httptest.NewServer(mux)
+	previous := bananoutils.GetMonkeyServer()
+	bananoutils.ChangeMonkeyServer(server.URL)
+	t.Cleanup(func() {
+		bananoutils.ChangeMonkeyServer(previous)
+		server.Close()
+	})
+	return server
+}
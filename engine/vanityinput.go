@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// vanityLabels maps the labels vanity generators print in front of keys and
+// addresses to what they hold, an empty key type is an address.
+var vanityLabels = map[string]bananoutils.KeyType{
+	"private key": bananoutils.KeyTypeAdhoc,
+	"private":     bananoutils.KeyTypeAdhoc,
+	"priv":        bananoutils.KeyTypeAdhoc,
+	"secret key":  bananoutils.KeyTypeAdhoc,
+	"key":         bananoutils.KeyTypeAdhoc,
+	"seed":        bananoutils.KeyTypeSeed,
+	"wallet seed": bananoutils.KeyTypeSeed,
+	"account":     "",
+	"address":     "",
+	"public":      "",
+}
+
+// vanityPair is a key waiting for the address printed after it, or the
+// other way around.
+type vanityPair struct {
+	secret  string
+	keyType bananoutils.KeyType
+	address string
+}
+
+// ReadVanityWallets reads the output of text vanity generators like
+// nano-vanity, in their labelled form:
+//
+//	Found matching account!
+//	Private Key: <hex key>
+//	Account:     xrb_<address>
+//
+// with Seed in place of Private Key for seeds, or their simple form of a key
+// and its address on one line. Every address has to belong to its key, pairs
+// that do not match are skipped. Progress and other lines are ignored.
+func ReadVanityWallets(ctx context.Context, r io.Reader) <-chan Wallet {
+	var pending vanityPair
+	return readWallets(ctx, r, func(line string) (Wallet, bool, error) {
+		label, value, labelled := splitVanityLine(line)
+		if !labelled {
+			fields := strings.Fields(line)
+			if len(fields) != 2 || !looksLikeKey(fields[0]) {
+				return Wallet{}, false, nil
+			}
+			pending = vanityPair{}
+			wallet, err := secretWallet(fields[0], walletKeyType, false, fields[1])
+			return wallet, err == nil, err
+		}
+
+		keyType, known := vanityLabels[label]
+		if !known {
+			return Wallet{}, false, nil
+		}
+		// A pending pair only ever holds one half, the other half replaces
+		// it and starts over if the same half shows up twice.
+		var err error
+		if keyType == "" {
+			if pending.address != "" {
+				err = errors.New("address without a key next to it")
+			}
+			pending.address = value
+		} else {
+			if pending.secret != "" {
+				err = errors.New("key without an address next to it")
+			}
+			pending.secret, pending.keyType = value, keyType
+		}
+		if err != nil {
+			return Wallet{}, false, err
+		}
+		if pending.secret == "" || pending.address == "" {
+			return Wallet{}, false, nil
+		}
+		pair := pending
+		pending = vanityPair{}
+		// the label is only a hint, the address decides which key type it is
+		wallet, err := secretWallet(pair.secret, pair.keyType, false, pair.address)
+		return wallet, err == nil, err
+	})
+}
+
+// splitVanityLine splits "Label: value" lines, the label is lower cased.
+func splitVanityLine(line string) (label string, value string, ok bool) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	value = strings.TrimSpace(line[i+1:])
+	if value == "" || strings.Contains(value, " ") {
+		return "", "", false
+	}
+	return strings.ToLower(strings.TrimSpace(line[:i])), value, true
+}
+
+func looksLikeKey(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range strings.ToLower(s) {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestReadVanityWallets(t *testing.T) {
+	output := strings.Join([]string{
+		"Estimated number of iterations needed: 33554432",
+		"Found matching account!",
+		"Private Key: " + strings.ToUpper(testSeed),
+		"Account:     xrb_" + testAdhocAddress[4:],
+		"Found matching account!",
+		"Seed:    " + testZeroSeed,
+		"Account: nano_" + testZeroAddress[4:],
+		// labelled as a private key but the address says it is a seed
+		"Private Key: " + testSeed,
+		"Account:     " + testSeedAddress,
+		// simple output
+		testSeed + " " + testAdhocAddress,
+		// key that does not belong to the address
+		"Private Key: " + testZeroSeed,
+		"Account:     " + testSeedAddress,
+		// key without its address
+		"Private Key: " + testSeed,
+		"Seed: " + testZeroSeed,
+		"Account: " + testZeroAddress,
+		"Tried 1000000 keys",
+	}, "\n")
+	want := []engine.Wallet{
+		{Address: testAdhocAddress, Secret: testSeed, KeyType: bananoutils.KeyTypeAdhoc},
+		{Address: testZeroAddress, Secret: testZeroSeed, KeyType: bananoutils.KeyTypeSeed},
+		{Address: testSeedAddress, Secret: testSeed, KeyType: bananoutils.KeyTypeSeed},
+		{Address: testAdhocAddress, Secret: testSeed, KeyType: bananoutils.KeyTypeAdhoc},
+		{Address: testZeroAddress, Secret: testZeroSeed, KeyType: bananoutils.KeyTypeSeed},
+	}
+	var got []engine.Wallet
+	for wallet := range engine.ReadVanityWallets(context.Background(), strings.NewReader(output)) {
+		got = append(got, wallet)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d wallets, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("wallet %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
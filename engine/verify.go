@@ -0,0 +1,364 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	legionImage "github.com/steampoweredtaco/legion-van/image"
+)
+
+// Kinds of problems VerifyDir finds.
+const (
+	// ProblemUnreadable is a monKey json or manifest that cannot be read.
+	ProblemUnreadable = "unreadable"
+	// ProblemKey is a saved key that is not the key of the address.
+	ProblemKey = "key"
+	// ProblemTraits is a monKey the monkey server describes differently.
+	ProblemTraits = "traits"
+	// ProblemImage is an image that is not the monKey of the address.
+	ProblemImage = "image"
+	// ProblemMissingImage is a monKey json without an image next to it.
+	ProblemMissingImage = "missing_image"
+	// ProblemOrphanImage is an image without a monKey json next to it.
+	ProblemOrphanImage = "orphan_image"
+	// ProblemTampered is a file that changed since it was listed in a
+	// manifest.
+	ProblemTampered = "tampered"
+	// ProblemMissingFile is a file listed in a manifest that is gone.
+	ProblemMissingFile = "missing_file"
+)
+
+// Problem is something wrong with a file in the found directory.
+type Problem struct {
+	File   string
+	Kind   string
+	Detail string
+}
+
+func (problem Problem) String() string {
+	return fmt.Sprintf("%s: %s, %s", problem.File, problem.Kind, problem.Detail)
+}
+
+// VerifyOptions changes what VerifyDir checks.
+type VerifyOptions struct {
+	// Offline leaves out the checks of traits and images against the monkey
+	// server.
+	Offline bool
+	// Passphrase is asked once for the passphrase of monKeys saved with
+	// --encrypt, when nil their keys are not checked.
+	Passphrase func() ([]byte, error)
+	// Fix saves missing images again from the monkey server, in the format of
+	// ImageFormat, like "png".
+	Fix         bool
+	ImageFormat string
+}
+
+// VerifyReport is what VerifyDir found.
+type VerifyReport struct {
+	// Checked is how many monKey json files were checked.
+	Checked int
+	// Encrypted is how many monKeys were not key checked for lack of a
+	// passphrase.
+	Encrypted int
+	// Fixed are the images saved again.
+	Fixed    []string
+	Problems []Problem
+}
+
+var imageExtensions = []string{".png", ".svg"}
+
+// VerifyDir checks every monKey saved in dir: that its key belongs to its
+// address, that the monkey server agrees with its traits and image, that no
+// json is without its image or image without its json, and that no file
+// listed in a sha256 manifest changed.
+func VerifyDir(ctx context.Context, dir string, options VerifyOptions) (VerifyReport, error) {
+	var report VerifyReport
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return report, fmt.Errorf("could not read directory: %w", err)
+	}
+	monkeyFiles := make(map[string]bool)
+	imageFiles := make(map[string]bool)
+	var manifests []string
+	for _, entry := range entries {
+		name := entry.Name()
+		// temporary files and the pending directory are not finished finds
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		switch filepath.Ext(name) {
+		case ".json":
+			monkeyFiles[name] = true
+		case ".png", ".svg":
+			imageFiles[name] = true
+		case ".sha256":
+			manifests = append(manifests, name)
+		}
+	}
+
+	problem := func(file string, kind string, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, Problem{File: file, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+	}
+	verifier := &monkeyVerifier{dir: dir, options: options}
+	fixed := make(map[string]bool)
+	for _, name := range sortedNames(monkeyFiles) {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		report.Checked++
+		base := strings.TrimSuffix(name, ".json")
+		var images []string
+		for _, extension := range imageExtensions {
+			if imageFiles[base+extension] {
+				images = append(images, base+extension)
+				delete(imageFiles, base+extension)
+			}
+		}
+
+		monkey, err := LoadMonkeyFile(filepath.Join(dir, name))
+		if err != nil {
+			problem(name, ProblemUnreadable, "%s", err)
+			continue
+		}
+		if monkey.EncryptedSecret != nil && options.Passphrase == nil {
+			report.Encrypted++
+		} else if err := verifier.checkKey(monkey); err != nil {
+			problem(name, ProblemKey, "%s", err)
+		}
+		if !options.Offline {
+			err = verifier.checkTraits(ctx, monkey)
+			if err != nil {
+				problem(name, ProblemTraits, "%s", err)
+			}
+			for _, imageName := range images {
+				err = verifier.checkImage(ctx, monkey, imageName)
+				if err != nil {
+					problem(imageName, ProblemImage, "%s", err)
+				}
+			}
+		}
+		if len(images) > 0 {
+			continue
+		}
+		if !options.Fix {
+			problem(name, ProblemMissingImage, "no .png or .svg next to it")
+			continue
+		}
+		imageName, err := verifier.saveImage(ctx, monkey, base)
+		if err != nil {
+			problem(name, ProblemMissingImage, "could not save it again: %s", err)
+			continue
+		}
+		fixed[imageName] = true
+		report.Fixed = append(report.Fixed, imageName)
+	}
+	for _, name := range sortedNames(imageFiles) {
+		problem(name, ProblemOrphanImage, "no monKey json next to it")
+	}
+
+	sort.Strings(manifests)
+	for _, manifest := range manifests {
+		sums, err := ReadManifest(filepath.Join(dir, manifest))
+		if err != nil {
+			problem(manifest, ProblemUnreadable, "%s", err)
+			continue
+		}
+		for _, name := range sortedSums(sums) {
+			// a file only in a manifest is a name, not a path
+			if filepath.Base(name) != name || fixed[name] {
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				problem(name, ProblemMissingFile, "listed in %s but cannot be read", manifest)
+				continue
+			}
+			sum := sha256.Sum256(data)
+			if hex.EncodeToString(sum[:]) != sums[name] {
+				problem(name, ProblemTampered, "changed since it was listed in %s", manifest)
+			}
+		}
+	}
+	return report, nil
+}
+
+// monkeyVerifier checks single monKeys for VerifyDir.
+type monkeyVerifier struct {
+	dir        string
+	options    VerifyOptions
+	passphrase []byte
+}
+
+// checkKey derives the address from the saved key.
+func (verifier *monkeyVerifier) checkKey(monkey MonkeyStats) error {
+	secret := monkey.PrivateKey.Reveal()
+	if monkey.EncryptedSecret != nil {
+		if verifier.passphrase == nil {
+			passphrase, err := verifier.options.Passphrase()
+			if err != nil {
+				return fmt.Errorf("could not read passphrase: %w", err)
+			}
+			verifier.passphrase = passphrase
+		}
+		privateKey, _, err := DecryptSecret(monkey, verifier.passphrase)
+		if err != nil {
+			return err
+		}
+		secret = privateKey.Reveal()
+	}
+
+	var address bananoutils.Account
+	switch monkey.KeyType {
+	case bananoutils.KeyTypeNone:
+		// tested from an address only input, there is no key
+		return nil
+	case bananoutils.KeyTypeSplit:
+		base, err := bananoutils.AddressToPub(bananoutils.Account(monkey.SplitBase))
+		if err != nil {
+			return fmt.Errorf("bad split_base: %w", err)
+		}
+		pub, err := bananoutils.SplitPublicKey(base, secret)
+		if err != nil {
+			return err
+		}
+		address = bananoutils.PubKeyToAddress(pub)
+	default:
+		if secret == "" {
+			return fmt.Errorf("no key is saved")
+		}
+		pub, _, err := bananoutils.KeypairFromSecret(monkey.KeyType, secret)
+		if err != nil {
+			return err
+		}
+		address = bananoutils.PubKeyToAddress(pub)
+	}
+	if string(address) != monkey.PublicAddress {
+		return fmt.Errorf("the %s key is for %s, not %s", monkey.KeyType, address, monkey.PublicAddress)
+	}
+	return nil
+}
+
+// checkTraits asks the monkey server for the traits of the address again.
+func (verifier *monkeyVerifier) checkTraits(ctx context.Context, monkey MonkeyStats) error {
+	fresh, err := FetchMonkeyStats(ctx, monkey.PublicAddress)
+	if err != nil {
+		return err
+	}
+	saved := []string{monkey.Glasses, monkey.Hat, monkey.Misc, monkey.Mouth, monkey.ShirtPants, monkey.Shoes, monkey.Tail}
+	server := []string{fresh.Glasses, fresh.Hat, fresh.Misc, fresh.Mouth, fresh.ShirtPants, fresh.Shoes, fresh.Tail}
+	for i := range saved {
+		if saved[i] != server[i] {
+			return fmt.Errorf("saved %s but the monkey server says %s", saved[i], server[i])
+		}
+	}
+	return nil
+}
+
+// checkImage compares a saved image with the monKey from the monkey server,
+// png images by their pixels as the encoder may differ.
+func (verifier *monkeyVerifier) checkImage(ctx context.Context, monkey MonkeyStats, imageName string) error {
+	saved, err := ioutil.ReadFile(filepath.Join(verifier.dir, imageName))
+	if err != nil {
+		return err
+	}
+	monkeySVG, err := bananoutils.GrabMonkey(ctx, bananoutils.Account(monkey.PublicAddress), legionImage.SVGFormat)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(imageName) == ".svg" {
+		fresh, err := io.ReadAll(monkeySVG)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(saved, fresh) {
+			return fmt.Errorf("not the image the monkey server has for %s", monkey.PublicAddress)
+		}
+		return nil
+	}
+	convert, err := imageConverter("png")
+	if err != nil {
+		return err
+	}
+	fresh, err := convert(monkeySVG)
+	if err != nil {
+		return err
+	}
+	if !samePixels(saved, fresh) {
+		return fmt.Errorf("not the image the monkey server has for %s", monkey.PublicAddress)
+	}
+	return nil
+}
+
+// saveImage saves the image of monkey again as base and the image format.
+func (verifier *monkeyVerifier) saveImage(ctx context.Context, monkey MonkeyStats, base string) (string, error) {
+	convert, err := imageConverter(verifier.options.ImageFormat)
+	if err != nil {
+		return "", err
+	}
+	monkeySVG, err := bananoutils.GrabMonkey(ctx, bananoutils.Account(monkey.PublicAddress), legionImage.SVGFormat)
+	if err != nil {
+		return "", err
+	}
+	data, err := convert(monkeySVG)
+	if err != nil {
+		return "", err
+	}
+	imageName := base + "." + strings.ToLower(verifier.options.ImageFormat)
+	err = writeFileAtomic(filepath.Join(verifier.dir, imageName), data, 0600)
+	if err != nil {
+		return "", err
+	}
+	return imageName, nil
+}
+
+func samePixels(a []byte, b []byte) bool {
+	imageA, errA := png.Decode(bytes.NewReader(a))
+	imageB, errB := png.Decode(bytes.NewReader(b))
+	if errA != nil || errB != nil || imageA.Bounds() != imageB.Bounds() {
+		return false
+	}
+	bounds := imageA.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !sameColor(imageA, imageB, x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sameColor(a image.Image, b image.Image, x int, y int) bool {
+	r1, g1, b1, a1 := a.At(x, y).RGBA()
+	r2, g2, b2, a2 := b.At(x, y).RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func sortedSums(sums map[string]string) []string {
+	sorted := make([]string, 0, len(sums))
+	for name := range sums {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
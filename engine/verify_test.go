@@ -0,0 +1,166 @@
+package engine_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func problemsString(problems []engine.Problem) string {
+	var found []string
+	for _, problem := range problems {
+		found = append(found, problem.Kind+" "+problem.File)
+	}
+	return strings.Join(found, "\n")
+}
+
+func TestVerifyDir(t *testing.T) {
+	var changed int32
+	newStubMonkeyServer(t, func(address string) map[string]string {
+		if address == testZeroAddress && atomic.LoadInt32(&changed) == 1 {
+			return map[string]string{"hat": "cap", "mouth": "cigar"}
+		}
+		return map[string]string{"hat": "crown", "mouth": "cigar"}
+	})
+	targetDir := t.TempDir()
+	sink := engine.NewFanOut(engine.NewDirectorySink(targetDir), engine.NewManifestSink(targetDir))
+	err := sink.Open("session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	monkeys := make(chan engine.MonkeyStats, 3)
+	for _, saved := range []struct {
+		name    string
+		address string
+		keyType bananoutils.KeyType
+	}{
+		{"Good", testSeedAddress, bananoutils.KeyTypeSeed},
+		{"Bare", testAdhocAddress, bananoutils.KeyTypeAdhoc},
+		// the seed of another address
+		{"Liar", testZeroAddress, bananoutils.KeyTypeSeed},
+	} {
+		monkey, err := engine.FetchMonkeyStats(context.Background(), saved.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		monkey.SillyName = saved.name
+		monkey.PrivateKey = bananoutils.Secret(testSeed)
+		monkey.KeyType = saved.keyType
+		monkeys <- monkey
+	}
+	close(monkeys)
+	err = engine.OutputMonkeys(sink, "svg", engine.OutputOptions{}, monkeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := engine.VerifyDir(context.Background(), targetDir, engine.VerifyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 3 || len(report.Problems) != 1 {
+		t.Fatalf("expected only the wrong key in 3 monKeys, got %d %s", report.Checked, problemsString(report.Problems))
+	}
+
+	atomic.StoreInt32(&changed, 1)
+	err = os.Remove(filepath.Join(targetDir, "Bare_"+testAdhocAddress+".svg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(targetDir, "Good_"+testSeedAddress+".svg"), []byte("<svg/>"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(targetDir, "Stray_"+testSeedAddress+".svg"), []byte("<svg/>"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = engine.VerifyDir(context.Background(), targetDir, engine.VerifyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"missing_image Bare_" + testAdhocAddress + ".json",
+		"image Good_" + testSeedAddress + ".svg",
+		"key Liar_" + testZeroAddress + ".json",
+		"traits Liar_" + testZeroAddress + ".json",
+		"orphan_image Stray_" + testSeedAddress + ".svg",
+		"missing_file Bare_" + testAdhocAddress + ".svg",
+		"tampered Good_" + testSeedAddress + ".svg",
+	}, "\n")
+	if got := problemsString(report.Problems); got != expected {
+		t.Errorf("expected problems\n%s\ngot\n%s", expected, got)
+	}
+
+	// offline only the files are checked, and the missing image is saved again
+	report, err = engine.VerifyDir(context.Background(), targetDir, engine.VerifyOptions{Offline: true, Fix: true, ImageFormat: "svg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = strings.Join([]string{
+		"key Liar_" + testZeroAddress + ".json",
+		"orphan_image Stray_" + testSeedAddress + ".svg",
+		"tampered Good_" + testSeedAddress + ".svg",
+	}, "\n")
+	if got := problemsString(report.Problems); got != expected {
+		t.Errorf("expected problems\n%s\ngot\n%s", expected, got)
+	}
+	if len(report.Fixed) != 1 || report.Fixed[0] != "Bare_"+testAdhocAddress+".svg" {
+		t.Fatalf("expected the missing image to be saved again, got %v", report.Fixed)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, report.Fixed[0])); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	targetDir := t.TempDir()
+	sink := engine.NewManifestSink(targetDir)
+	err := sink.Open("session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var monkey engine.MonkeyStats
+	monkey.SillyName = "Sum"
+	monkey.PublicAddress = testSeedAddress
+	err = sink.Write(engine.FoundMonkey{Monkey: monkey, JSON: []byte("{}"), Image: []byte("<svg/>"), ImageExtension: ".svg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a session only gets one manifest
+	if sink.Open("session-1") == nil {
+		t.Error("expected the manifest of the session not to be replaced")
+	}
+
+	sums, err := engine.ReadManifest(filepath.Join(targetDir, engine.ManifestName("session-1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"Sum_" + testSeedAddress + ".json": "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		"Sum_" + testSeedAddress + ".svg":  "d4dc56669143034f31aa309635d4113d9ad76a02b1739da22c965ed2049be9e6",
+	}
+	for name, sum := range expected {
+		if sums[name] != sum {
+			t.Errorf("expected %s for %s, got %s", sum, name, sums[name])
+		}
+	}
+	if len(sums) != len(expected) {
+		t.Errorf("expected %d sums, got %v", len(expected), sums)
+	}
+}
@@ -2,41 +2,154 @@ package engine
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
 	"io"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/bbedward/crypto/ed25519"
 	"github.com/steampoweredtaco/legion-van/bananoutils"
 	"github.com/ugorji/go/codec"
 )
 
+var walletKeyType = bananoutils.KeyTypeSeed
+
+// SetKeyType changes the kind of secret generated for each tested account.
+func SetKeyType(keyType bananoutils.KeyType) {
+	walletKeyType = keyType
+}
+
+// splitBase is the public key split key searches add their partial keys to.
+var splitBase ed25519.PublicKey
+
+// SetSplitBase makes generated wallets partial keys of a split key search
+// for the account of pub, see bananoutils.CombineSplitKey.
+func SetSplitBase(pub ed25519.PublicKey) error {
+	err := bananoutils.CheckPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	walletKeyType = bananoutils.KeyTypeSplit
+	splitBase = pub
+	return nil
+}
+
+// SetEntropySource changes where the keys of generated wallets come from, nil
+// goes back to crypto/rand. Other sources have to pass
+// bananoutils.CheckEntropy first.
+func SetEntropySource(source io.Reader) error {
+	if source != nil {
+		err := bananoutils.CheckEntropy(source)
+		if err != nil {
+			return err
+		}
+	}
+	bananoutils.SetEntropySource(source)
+	return nil
+}
+
+// Wallet is an account to test for a monKey and the secret it belongs to,
+// address only wallets have KeyTypeNone and no secret. Split key wallets have
+// the address of the account their partial secret has to be combined with.
+type Wallet struct {
+	Address   string
+	Secret    bananoutils.Secret
+	KeyType   bananoutils.KeyType
+	SplitBase string
+}
+
 type walletsDB struct {
 	publicAccounts              []string
-	publicAccountToWalletLookup map[string]string
+	publicAccountToWalletLookup map[string]Wallet
 }
 
-func generateManyWallets(amount uint) walletsDB {
-	var accountsToWalletKey = make(map[string]string, amount)
-	accounts := make([]string, 0, amount)
+// how long generating a batch of wallets for an address pattern may take
+// before the ones found so far are sent on their own.
+const patternBatchTimeout = 10 * time.Second
 
-	for i := uint(0); i < amount; i++ {
-		privateWalletSeed, publicAccount, err := bananoutils.GeneratePrivateKeyAndFirstPublicAddress()
+// generateKeysPerBatch is how many keys are made from the entropy source at a
+// time when looking for an address pattern.
+const generateKeysPerBatch = 256
+
+// generateManyWallets makes amount random wallets whose address matches
+// pattern, a nil pattern matches every address, skipped is how many keys the
+// pattern left out. Rare patterns can return fewer once a batch takes too
+// long, or none if ctx is done first.
+func generateManyWallets(ctx context.Context, amount uint, pattern *AddressPattern) (db walletsDB, skipped uint64) {
+	wallets := make([]Wallet, 0, amount)
+
+	deriver := bananoutils.NewKeyDeriver()
+	keys := uint(generateKeysPerBatch)
+	if pattern == nil && amount < keys {
+		keys = amount
+	}
+	secrets := make([]byte, keys*bananoutils.KeySize)
+	pubs := make([]byte, keys*bananoutils.KeySize)
+	next := len(secrets)
+	var base string
+	if walletKeyType == bananoutils.KeyTypeSplit {
+		err := deriver.SetSplitBase(splitBase)
 		if err != nil {
 			panic(err)
 		}
-		publicAccountStr := string(publicAccount)
+		base = string(bananoutils.PubKeyToAddress(splitBase))
+	}
+
+	started := time.Now()
+	for tries := 1; uint(len(wallets)) < amount; tries++ {
+		if next == len(secrets) {
+			err := deriver.Generate(walletKeyType, pubs, secrets)
+			if err != nil {
+				panic(err)
+			}
+			next = 0
+		}
+		secret, pub := secrets[next:next+bananoutils.KeySize], pubs[next:next+bananoutils.KeySize]
+		next += bananoutils.KeySize
 
-		accountsToWalletKey[publicAccountStr] = privateWalletSeed
-		accounts = append(accounts, publicAccountStr)
+		publicAccount := bananoutils.PubKeyToAddress(pub)
+		if pattern.Match(string(publicAccount)) {
+			wallets = append(wallets, Wallet{Address: string(publicAccount), Secret: bananoutils.Secret(hex.EncodeToString(secret)), KeyType: walletKeyType, SplitBase: base})
+		} else {
+			skipped++
+		}
+		if pattern == nil || tries%1024 != 0 {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if len(wallets) > 0 && time.Since(started) > patternBatchTimeout {
+			break
+		}
+	}
+	return newWalletsDB(wallets), skipped
+}
+
+// newWalletsDB indexes wallets by address, repeated addresses are only asked
+// for once.
+func newWalletsDB(wallets []Wallet) walletsDB {
+	db := walletsDB{
+		publicAccounts:              make([]string, 0, len(wallets)),
+		publicAccountToWalletLookup: make(map[string]Wallet, len(wallets)),
+	}
+	for _, wallet := range wallets {
+		if _, ok := db.publicAccountToWalletLookup[wallet.Address]; ok {
+			continue
+		}
+		db.publicAccountToWalletLookup[wallet.Address] = wallet
+		db.publicAccounts = append(db.publicAccounts, wallet.Address)
 	}
-	return walletsDB{publicAccounts: accounts, publicAccountToWalletLookup: accountsToWalletKey}
+	return db
 }
 
 func (db walletsDB) getAccounts() []string {
 	return db.publicAccounts
 }
 
-func (db walletsDB) lookupWalletSeed(publicAddress string) string {
+func (db walletsDB) lookupWallet(publicAddress string) Wallet {
 	return db.publicAccountToWalletLookup[publicAddress]
 }
 
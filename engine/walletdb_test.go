@@ -0,0 +1,58 @@
+package engine_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestDeterministicWallets(t *testing.T) {
+	defer engine.SetEntropySource(nil)
+	newStubMonkeyServer(t, nil)
+	search := func() []string {
+		err := engine.SetEntropySource(bananoutils.NewDeterministicEntropy([]byte("legion")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		monkeys, stats := engine.GenerateAndFilterMonkees(ctx, 5, engine.CmdLineFilter{})
+		go func() {
+			for range stats {
+			}
+		}()
+		// the first request is the first five wallets, they come back in any order
+		var found []string
+		for i := 0; i < 5; i++ {
+			found = append(found, (<-monkeys).PublicAddress)
+		}
+		cancel()
+		for range monkeys {
+		}
+		sort.Strings(found)
+		return found
+	}
+	first, again := search(), search()
+	for i := range first {
+		if first[i] != again[i] {
+			t.Errorf("wallet %d differs between runs: %s and %s", i, first[i], again[i])
+		}
+	}
+
+	err := engine.SetEntropySource(&zeroReader{})
+	if err == nil {
+		t.Error("expected a source of zeros to fail the health check")
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
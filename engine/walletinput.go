@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+)
+
+// ReadWallets reads wallets to test from r, one per line. A line is an
+// address, a hex seed or private key, a 24 word mnemonic, a key followed by
+// the address it belongs to, or a json object with the same fields as a saved
+// monKey of any schema version (address, seed, private_key and key_type). Hex keys
+// without a key type are read as wallet seeds, or ad-hoc keys after
+// SetKeyType, unless the address on the line says otherwise. Lines that cannot
+// be read are skipped with a warning naming only their line number.
+func ReadWallets(ctx context.Context, r io.Reader) <-chan Wallet {
+	return readWallets(ctx, r, func(line string) (Wallet, bool, error) {
+		wallet, err := parseWalletLine(line)
+		return wallet, err == nil, err
+	})
+}
+
+// readWallets sends the wallets parse finds in the lines of r, parse returns
+// false without an error for lines that do not finish a wallet.
+func readWallets(ctx context.Context, r io.Reader, parse func(line string) (Wallet, bool, error)) <-chan Wallet {
+	wallets := make(chan Wallet, 1000)
+	go func() {
+		defer close(wallets)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		var lineNumber, read, skipped int
+		for scanner.Scan() {
+			lineNumber++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			wallet, ok, err := parse(line)
+			if err != nil {
+				skipped++
+				log.Warnf("skipping input line %d: %s", lineNumber, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			read++
+			select {
+			case <-ctx.Done():
+				return
+			case wallets <- wallet:
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Errorf("could not read wallets after line %d: %s", lineNumber, err)
+		}
+		log.Infof("Read %d wallets from the input, skipped %d lines", read, skipped)
+	}()
+	return wallets
+}
+
+func parseWalletLine(line string) (Wallet, error) {
+	if strings.HasPrefix(line, "{") {
+		return parseWalletJSON(line)
+	}
+	fields := strings.Fields(line)
+	switch len(fields) {
+	case 1:
+		if _, err := bananoutils.AddressPrefix(bananoutils.Account(fields[0])); err == nil {
+			return addressWallet(fields[0])
+		}
+		return secretWallet(fields[0], walletKeyType, false, "")
+	case 2:
+		return secretWallet(fields[0], walletKeyType, false, fields[1])
+	case 24:
+		seed, err := bananoutils.MnemonicToSeed(line)
+		if errors.Is(err, bananoutils.ErrMnemonicWord) {
+			// do not log the word, it is part of a secret
+			return Wallet{}, bananoutils.ErrMnemonicWord
+		}
+		if err != nil {
+			return Wallet{}, err
+		}
+		return secretWallet(hex.EncodeToString(seed), bananoutils.KeyTypeSeed, true, "")
+	}
+	return Wallet{}, errors.New("expected an address, a key, a key and address, a mnemonic or a json object")
+}
+
+func parseWalletJSON(line string) (Wallet, error) {
+	var entry struct {
+		PublicAddress string `json:"public_address"`
+		Address       string `json:"address"`
+		Account       string `json:"account"`
+		PrivateKey    string `json:"private_key"`
+		Seed          string `json:"seed"`
+		KeyType       string `json:"key_type"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(line)))
+	err := decoder.Decode(&entry)
+	if err != nil {
+		// the json error can quote the line, which may hold a secret
+		return Wallet{}, errors.New("not a valid json object")
+	}
+	address := entry.PublicAddress
+	if address == "" {
+		address = entry.Address
+	}
+	if address == "" {
+		address = entry.Account
+	}
+	switch {
+	case entry.Seed != "":
+		return secretWallet(entry.Seed, bananoutils.KeyTypeSeed, true, address)
+	case entry.PrivateKey != "" && entry.KeyType != "":
+		return secretWallet(entry.PrivateKey, bananoutils.KeyType(entry.KeyType), true, address)
+	case entry.PrivateKey != "":
+		return secretWallet(entry.PrivateKey, walletKeyType, false, address)
+	case address != "":
+		return addressWallet(address)
+	}
+	return Wallet{}, errors.New("json object has no address, seed or private_key")
+}
+
+// addressWallet is a wallet for an address without its secret, the address is
+// always given to the monkey server with the ban_ prefix.
+func addressWallet(address string) (Wallet, error) {
+	pub, err := bananoutils.AddressToPub(bananoutils.Account(address))
+	if err != nil {
+		return Wallet{}, err
+	}
+	return Wallet{Address: string(bananoutils.PubKeyToAddress(pub)), KeyType: bananoutils.KeyTypeNone}, nil
+}
+
+// secretWallet derives the account of secret. When address is given it has to
+// match, and a key type that was only guessed is switched if that makes it
+// match.
+func secretWallet(secret string, keyType bananoutils.KeyType, explicit bool, address string) (Wallet, error) {
+	secret = strings.ToLower(secret)
+	keyTypes := []bananoutils.KeyType{keyType}
+	if !explicit && address != "" {
+		for _, other := range []bananoutils.KeyType{bananoutils.KeyTypeSeed, bananoutils.KeyTypeAdhoc} {
+			if other != keyType {
+				keyTypes = append(keyTypes, other)
+			}
+		}
+	}
+	var want []byte
+	if address != "" {
+		var err error
+		want, err = bananoutils.AddressToPub(bananoutils.Account(address))
+		if err != nil {
+			return Wallet{}, err
+		}
+	}
+	for _, keyType := range keyTypes {
+		pub, _, err := bananoutils.KeypairFromSecret(keyType, secret)
+		if err != nil {
+			return Wallet{}, err
+		}
+		if want != nil && !bytes.Equal(pub, want) {
+			continue
+		}
+		return Wallet{Address: string(bananoutils.PubKeyToAddress(pub)), Secret: bananoutils.Secret(secret), KeyType: keyType}, nil
+	}
+	return Wallet{}, fmt.Errorf("key does not belong to %s", address)
+}
@@ -0,0 +1,96 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+const (
+	testSeed          = "deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
+	testSeedAddress   = "ban_3wtsduys8b7jkbfwwfzx3jgpgpsi9b8zurfe9bp1p5cdxkqiz7a5wxcoo7ba"
+	testAdhocAddress  = "ban_1k63i7emu4zhsdgi3uaxptpamc5xkwrk68x37bbxghnkybwh9wz4qajrkyst"
+	testZeroSeed      = "0000000000000000000000000000000000000000000000000000000000000000"
+	testZeroSeedWords = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"
+	testZeroAddress   = "ban_3i1aq1cchnmbn9x5rsbap8b15akfh7wj7pwskuzi7ahz8oq6cobd99d4r3b7"
+)
+
+var testWalletInput = strings.Join([]string{
+	"# wallets from another tool",
+	testSeed,
+	testSeed + " " + testAdhocAddress,
+	"",
+	`{"private_key": "` + testSeed + `", "key_type": "adhoc"}`,
+	`{"seed": "` + testZeroSeed + `"}`,
+	testZeroSeedWords,
+	"nano_" + testSeedAddress[4:],
+	"not-a-wallet",
+	testSeed + " " + testZeroAddress,
+	`{"private_key": `,
+}, "\n")
+
+func TestReadWallets(t *testing.T) {
+	want := []engine.Wallet{
+		{Address: testSeedAddress, Secret: testSeed, KeyType: bananoutils.KeyTypeSeed},
+		{Address: testAdhocAddress, Secret: testSeed, KeyType: bananoutils.KeyTypeAdhoc},
+		{Address: testAdhocAddress, Secret: testSeed, KeyType: bananoutils.KeyTypeAdhoc},
+		{Address: testZeroAddress, Secret: testZeroSeed, KeyType: bananoutils.KeyTypeSeed},
+		{Address: testZeroAddress, Secret: testZeroSeed, KeyType: bananoutils.KeyTypeSeed},
+		{Address: testSeedAddress, KeyType: bananoutils.KeyTypeNone},
+	}
+	var got []engine.Wallet
+	for wallet := range engine.ReadWallets(context.Background(), strings.NewReader(testWalletInput)) {
+		got = append(got, wallet)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d wallets, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("wallet %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestFilterMonkees(t *testing.T) {
+	newStubMonkeyServer(t, func(address string) map[string]string {
+		if address == testAdhocAddress {
+			return map[string]string{"hat": "crown"}
+		}
+		return map[string]string{"hat": "none"}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wallets := engine.ReadWallets(ctx, strings.NewReader(testWalletInput))
+	monkeys, stats := engine.FilterMonkees(ctx, wallets, 100, engine.CmdLineFilter{Hat: []string{"crown"}})
+
+	var total, found uint64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for delta := range stats {
+			total += delta.Total
+			found += delta.Found
+		}
+	}()
+	var matched []engine.MonkeyStats
+	for monkey := range monkeys {
+		matched = append(matched, monkey)
+	}
+	<-done
+
+	if len(matched) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matched))
+	}
+	monkey := matched[0]
+	if monkey.PublicAddress != testAdhocAddress || monkey.KeyType != bananoutils.KeyTypeAdhoc || monkey.PrivateKey.Reveal() != testSeed {
+		t.Errorf("match lost its wallet: %s %s", monkey.PublicAddress, monkey.KeyType)
+	}
+	// repeated addresses in a batch are only tested once
+	if total != 3 || found != 1 {
+		t.Errorf("expected 3 tested and 1 found, got %d and %d", total, found)
+	}
+}
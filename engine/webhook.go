@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Webhook event types.
+const (
+	EventSessionStarted  = "session_started"
+	EventMonkeyFound     = "monkey_found"
+	EventError           = "error"
+	EventSessionFinished = "session_finished"
+)
+
+// WebhookSignatureHeader holds the hex HMAC-SHA256 of the request body made
+// with the webhook secret, prefixed with sha256=.
+const WebhookSignatureHeader = "X-Legion-Van-Signature"
+
+// webhookQueueSize is how many events can wait to be posted, more are dropped
+// so a slow receiver never holds up the search.
+const webhookQueueSize = 256
+
+// WebhookStats are the totals of the session so far.
+type WebhookStats struct {
+	Total         uint64 `json:"total"`
+	Found         uint64 `json:"found"`
+	TotalRequests uint64 `json:"total_requests"`
+}
+
+// WebhookEvent is the json posted to the webhook. Monkey is the saved json of
+// a found monKey without its secret, plain or encrypted.
+type WebhookEvent struct {
+	Type    string          `json:"type"`
+	Session string          `json:"session"`
+	Time    time.Time       `json:"time"`
+	Monkey  json.RawMessage `json:"monkey,omitempty"`
+	Stats   *WebhookStats   `json:"stats,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// Webhook posts the events of a search session to a url. It is a Sink for the
+// found monKeys and the session start and end, a logrus hook for errors, and
+// is given the stats deltas with AddStats. Events are posted in the
+// background one at a time and retried when the receiver fails.
+type Webhook struct {
+	// Attempts is how many times an event is posted before it is dropped.
+	Attempts int
+	// RetryDelay is the wait after the first failed attempt, it doubles
+	// after each one.
+	RetryDelay time.Duration
+	// CloseGrace is how long Close waits for the queued events, the ones
+	// still queued after it are dropped so a dead receiver cannot hold up
+	// the end of a search.
+	CloseGrace time.Duration
+
+	url    string
+	secret []byte
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	session string
+	stats   WebhookStats
+
+	mu      sync.Mutex
+	closed  bool
+	queue   chan WebhookEvent
+	started sync.Once
+	done    chan struct{}
+}
+
+// NewWebhook posts events to url, signed with secret when it is not empty.
+func NewWebhook(url string, secret string) *Webhook {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Webhook{
+		Attempts:   3,
+		RetryDelay: time.Second,
+		CloseGrace: 5 * time.Second,
+		url:        url,
+		secret:     []byte(secret),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		ctx:        ctx,
+		cancel:     cancel,
+		queue:      make(chan WebhookEvent, webhookQueueSize),
+		done:       make(chan struct{}),
+	}
+}
+
+// SignWebhook is the value of the WebhookSignatureHeader for body.
+func SignWebhook(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook reports whether signature was made for body with secret, for
+// receivers written in Go.
+func VerifyWebhook(secret []byte, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, body)), []byte(signature))
+}
+
+func (webhook *Webhook) Open(session string) error {
+	webhook.mu.Lock()
+	webhook.session = session
+	webhook.mu.Unlock()
+	webhook.started.Do(func() { go webhook.run() })
+	webhook.send(WebhookEvent{Type: EventSessionStarted})
+	return nil
+}
+
+func (webhook *Webhook) Write(found FoundMonkey) error {
+	monkey, err := withoutSecret(found.JSON)
+	if err != nil {
+		return err
+	}
+	var saved map[string]json.RawMessage
+	err = json.Unmarshal(monkey, &saved)
+	if err != nil {
+		return err
+	}
+	delete(saved, "encrypted_secret")
+	monkey, err = json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	webhook.send(WebhookEvent{Type: EventMonkeyFound, Monkey: monkey})
+	return nil
+}
+
+// Close sends the session finished event and waits up to CloseGrace for the
+// events still queued to be posted.
+func (webhook *Webhook) Close() error {
+	webhook.send(WebhookEvent{Type: EventSessionFinished, Stats: webhook.totals()})
+	webhook.mu.Lock()
+	if !webhook.closed {
+		webhook.closed = true
+		close(webhook.queue)
+	}
+	webhook.mu.Unlock()
+	webhook.started.Do(func() { close(webhook.done) })
+	grace := time.NewTimer(webhook.CloseGrace)
+	defer grace.Stop()
+	select {
+	case <-webhook.done:
+	case <-grace.C:
+		webhook.cancel()
+		<-webhook.done
+	}
+	webhook.cancel()
+	return nil
+}
+
+// AddStats adds a stats delta of the search to the session totals.
+func (webhook *Webhook) AddStats(delta Stats) {
+	atomic.AddUint64(&webhook.stats.Total, delta.Total)
+	atomic.AddUint64(&webhook.stats.Found, delta.Found)
+	atomic.AddUint64(&webhook.stats.TotalRequests, delta.TotalRequests)
+}
+
+func (webhook *Webhook) totals() *WebhookStats {
+	return &WebhookStats{
+		Total:         atomic.LoadUint64(&webhook.stats.Total),
+		Found:         atomic.LoadUint64(&webhook.stats.Found),
+		TotalRequests: atomic.LoadUint64(&webhook.stats.TotalRequests),
+	}
+}
+
+func (webhook *Webhook) Levels() []log.Level {
+	return []log.Level{log.ErrorLevel}
+}
+
+// Fire sends error log entries as error events, add it after RedactHook.
+func (webhook *Webhook) Fire(entry *log.Entry) error {
+	webhook.send(WebhookEvent{Type: EventError, Error: entry.Message, Stats: webhook.totals()})
+	return nil
+}
+
+func (webhook *Webhook) send(event WebhookEvent) {
+	webhook.mu.Lock()
+	defer webhook.mu.Unlock()
+	event.Session = webhook.session
+	event.Time = time.Now().UTC()
+	if webhook.closed {
+		return
+	}
+	select {
+	case webhook.queue <- event:
+	default:
+		// not logged as an error, that would be another event
+		log.Warnf("webhook is behind, dropped a %s event", event.Type)
+	}
+}
+
+func (webhook *Webhook) run() {
+	defer close(webhook.done)
+	var dropped int
+	for event := range webhook.queue {
+		if webhook.ctx.Err() != nil {
+			dropped++
+			continue
+		}
+		err := webhook.post(event)
+		if err != nil {
+			log.Warnf("could not post %s event to the webhook: %s", event.Type, err)
+		}
+	}
+	if dropped > 0 {
+		log.Warnf("webhook took too long, dropped the last %d events", dropped)
+	}
+}
+
+// post sends event, retrying network errors and server errors.
+func (webhook *Webhook) post(event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	delay := webhook.RetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := webhook.postOnce(event.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= webhook.Attempts {
+			return err
+		}
+		select {
+		case <-webhook.ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (webhook *Webhook) postOnce(eventType string, body []byte) (retry bool, err error) {
+	request, err := http.NewRequestWithContext(webhook.ctx, "POST", webhook.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Legion-Van-Event", eventType)
+	if len(webhook.secret) > 0 {
+		request.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.secret, body))
+	}
+	response, err := webhook.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook answered %s", response.Status)
+	return response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests, err
+}
@@ -0,0 +1,150 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+func TestWebhook(t *testing.T) {
+	secret := []byte("hush")
+	var mu sync.Mutex
+	var events []engine.WebhookEvent
+	var requests int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		// the first attempt fails to test the retry
+		if requests == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !engine.VerifyWebhook(secret, body, r.Header.Get(engine.WebhookSignatureHeader)) {
+			t.Errorf("bad signature for %s", body)
+		}
+		var event engine.WebhookEvent
+		err = json.Unmarshal(body, &event)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if r.Header.Get("X-Legion-Van-Event") != event.Type {
+			t.Errorf("event header %q does not match %q", r.Header.Get("X-Legion-Van-Event"), event.Type)
+		}
+		events = append(events, event)
+	}))
+	defer receiver.Close()
+
+	webhook := engine.NewWebhook(receiver.URL, string(secret))
+	webhook.RetryDelay = time.Millisecond
+	err := webhook.Open("session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := testFoundMonkey("Hooked")
+	found.JSON = []byte(`{"schema_version":2,"seed":"` + testSeed + `","mnemonic":"words","address":"` + testSeedAddress + `"}`)
+	err = webhook.Write(found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook.AddStats(engine.Stats{Total: 10, Found: 1, TotalRequests: 2})
+	webhook.AddStats(engine.Stats{Total: 5, TotalRequests: 1})
+	webhook.Fire(&log.Entry{Message: "sad monKey"})
+	err = webhook.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// closed webhooks drop events instead of panicking
+	webhook.Fire(&log.Entry{Message: "too late"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+		if event.Session != "session-1" {
+			t.Errorf("expected session-1, got %q", event.Session)
+		}
+	}
+	expected := []string{engine.EventSessionStarted, engine.EventMonkeyFound, engine.EventError, engine.EventSessionFinished}
+	if strings.Join(types, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected events %v, got %v", expected, types)
+	}
+	monkey := string(events[1].Monkey)
+	if strings.Contains(monkey, testSeed) || strings.Contains(monkey, "words") || !strings.Contains(monkey, testSeedAddress) {
+		t.Errorf("found event must have the address but no secret: %s", monkey)
+	}
+	if events[2].Error != "sad monKey" {
+		t.Errorf("expected the error message, got %q", events[2].Error)
+	}
+	if stats := events[3].Stats; stats == nil || stats.Total != 15 || stats.Found != 1 || stats.TotalRequests != 3 {
+		t.Errorf("expected the session totals, got %+v", stats)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer receiver.Close()
+
+	webhook := engine.NewWebhook(receiver.URL, "")
+	webhook.RetryDelay = time.Millisecond
+	webhook.Open("session-2")
+	webhook.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	// client errors are not retried
+	if requests != 2 {
+		t.Errorf("expected one request per event, got %d", requests)
+	}
+}
+
+func TestWebhookCloseDoesNotWaitForDeadReceiver(t *testing.T) {
+	stop := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// accepts the request and never answers
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+	defer receiver.Close()
+	defer close(stop)
+
+	webhook := engine.NewWebhook(receiver.URL, "")
+	webhook.CloseGrace = 50 * time.Millisecond
+	webhook.Open("session-3")
+	for i := 0; i < 10; i++ {
+		webhook.Write(engine.FoundMonkey{JSON: []byte(`{"address":"` + testSeedAddress + `"}`)})
+	}
+	closed := make(chan struct{})
+	go func() {
+		webhook.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for a receiver that never answers")
+	}
+}
@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	code.rocketnine.space/tslocum/cview v1.5.6
+	filippo.io/edwards25519 v1.0.0
 	github.com/Pallinder/go-randomdata v1.2.0
 	github.com/bbedward/crypto/ed25519 v0.0.0-20200408155757-fff4d9311ac0
 	github.com/disintegration/imaging v1.6.2
@@ -14,8 +15,10 @@ require (
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.4.0 // indirect
 	github.com/ugorji/go/codec v1.2.6
+	go.etcd.io/bbolt v1.3.6
 	golang.org/x/crypto v0.31.0
 	golang.org/x/image v0.0.0-20210628002857-a66eb6448b8d
+	golang.org/x/term v0.27.0
 	gopkg.in/gographics/imagick.v3 v3.4.0
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -7,6 +7,8 @@ import (
 	"context"
 	"fmt"
 	"image"
+	"io"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -41,9 +43,16 @@ type MainApp struct {
 	once         sync.Once
 	endTime      time.Time
 	pipeMode     bool
+	pipeOutput   io.Writer
 	odds         float64
 }
 
+// SetPipeOutput changes where stats and logs are printed without the gui,
+// stdout by default.
+func (a *MainApp) SetPipeOutput(w io.Writer) {
+	a.pipeOutput = w
+}
+
 func (a *MainApp) GetTotalStat() uint64 {
 	return atomic.LoadUint64(&a.runtimeStats.Total)
 }
@@ -104,7 +113,7 @@ func (a *MainApp) UpdateSpeed() {
 		if duration == 0 {
 			return
 		}
-		fmt.Println(statText)
+		fmt.Fprintln(a.pipeOutput, statText)
 	}
 }
 
@@ -123,7 +132,7 @@ func (a *MainApp) UpdateTotal() {
 	if !a.pipeMode {
 		a.app.QueueUpdateDraw(func() {}, a.total)
 	} else {
-		fmt.Println(statText)
+		fmt.Fprintln(a.pipeOutput, statText)
 	}
 
 }
@@ -182,7 +191,7 @@ func (a *MainApp) processLogMessages() {
 				a.logview.ScrollToEnd()
 			}, a.logview)
 		} else {
-			fmt.Println(msg)
+			fmt.Fprintln(a.pipeOutput, msg)
 		}
 
 	}
@@ -192,6 +201,7 @@ func NewMainApp(ctx context.Context, mainCancel context.CancelFunc, title string
 	mainApp := new(MainApp)
 	mainApp.odds = odds
 	mainApp.pipeMode = pipeMode
+	mainApp.pipeOutput = os.Stdout
 	mainApp.ctx, mainApp.mainCancel = ctx, mainCancel
 	mainApp.logChan = make(chan *logrus.Entry, 5)
 	log.AddHook(mainApp)
@@ -264,6 +274,12 @@ func (mainApp *MainApp) ForceQuit() {
 	mainApp.app.QueueUpdate(func() { mainApp.app.Stop() })
 }
 
+// Stop gives the terminal back right away without the graceful shutdown of
+// Quit, for quitting the process at once.
+func (m *MainApp) Stop() {
+	m.app.Stop()
+}
+
 func (m *MainApp) Quit() {
 	// notify all users of the gui before shutting down the gui
 	logrus.Debug("canceling main")
@@ -327,8 +343,13 @@ func (m *MainApp) Run(endTime time.Time) {
 	m.listenForPreviews()
 	go func() {
 		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
 		for {
-			<-ticker.C
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+			}
 			m.UpdateSpeed()
 			m.UpdateTotal()
 		}
@@ -344,8 +365,12 @@ func (m *MainApp) Run(endTime time.Time) {
 		if !m.pipeMode {
 			return
 		}
-		<-time.After(time.Until(endTime))
-		m.mainCancel()
+		select {
+		case <-m.ctx.Done():
+		case <-time.After(time.Until(endTime)):
+		}
+		// without a terminal nothing else stops the gui
+		m.RequestQuit()
 	}()
 	wg.Wait()
 	m.cleanupGui()
@@ -354,9 +379,16 @@ func (m *MainApp) Run(endTime time.Time) {
 func (m *MainApp) DebugPressEsc() {
 	m.once.Do(func() { m.Quit() })
 }
+
+// RequestQuit starts the same graceful shutdown as pressing ESC and returns
+// right away, it can be called more than once.
+func (m *MainApp) RequestQuit() {
+	go m.once.Do(func() { m.Quit() })
+}
+
 func (mainApp *MainApp) HandleEvent(event *tcell.EventKey) *tcell.EventKey {
 	if event.Key() == tcell.KeyEscape {
-		go mainApp.once.Do(func() { mainApp.Quit() })
+		mainApp.RequestQuit()
 		return nil
 	}
 	return event
@@ -12,17 +12,30 @@ import (
 	legionImage "github.com/steampoweredtaco/legion-van/image"
 )
 
+// PreviewMonkeys sends a png preview of every monKey from monkeyDataChan to
+// previewChan, until the channel is closed or ctx is done.
 func PreviewMonkeys(ctx context.Context, previewChan chan<- MonkeyPreview, monkeyDataChan <-chan engine.MonkeyStats) {
 	if monkeyDataChan == nil {
 		return
 	}
 
 	for {
-
-		monkey := <-monkeyDataChan
+		var monkey engine.MonkeyStats
+		select {
+		case <-ctx.Done():
+			return
+		case next, ok := <-monkeyDataChan:
+			if !ok {
+				return
+			}
+			monkey = next
+		}
 		// start := time.Now()
 		// grab as svg as it is nicer to the server and we can convert it locally
-		monkeySVG, err := bananoutils.GrabMonkey(context.Background(), bananoutils.Account(monkey.PublicAddress), legionImage.SVGFormat)
+		monkeySVG, err := bananoutils.GrabMonkey(ctx, bananoutils.Account(monkey.PublicAddress), legionImage.SVGFormat)
+		if ctx.Err() != nil {
+			return
+		}
 		if err != nil {
 			log.Warnf("could not convert monkey to preview: %s %s", monkey.SillyName, err)
 			continue
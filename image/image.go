@@ -156,36 +156,60 @@ func DrawImgToScreen(screen termbox.Screen, img image.Image) {
 	termPixWidth, termPixelHeight := getTermCharPixelWxH(screen)
 	//termPixelRatio := float64(termPixWidth) / float64(termPixelHeight)
 	//termRatio := float64(termWidth) / float64(termHeight)
-	bounds := img.Bounds()
 
 	// resizedImg := imaging.Fill(img, termPixelHeight, termPixWidth, imaging.Center, imaging.Lanczos)
 	resizedImg := imaging.Fit(img, termPixelHeight, termPixWidth, imaging.Lanczos)
 
-	bounds = resizedImg.Bounds()
-	imgW, imgH := bounds.Dx(), bounds.Dy()
 	//imgRatio := float64(imgW) / float64(imgH)
 
-	pixelsPerConsoleX := float64(imgW) / float64(termWidth)
-	pixelsPerConsoleY := float64(imgH) / float64(termHeight)
-
 	screen.Clear()
-	for y := 0; y < termHeight; y++ {
-		for x := 0; x < termWidth; x++ {
+	halfBlocks(resizedImg, termWidth, termHeight, func(x, y int, top, bottom [3]int32) {
+		style := termbox.StyleDefault.
+			Background(termbox.NewRGBColor(top[0], top[1], top[2])).
+			Foreground(termbox.NewRGBColor(bottom[0], bottom[1], bottom[2]))
+		screen.SetContent(x, y, '▄', nil, style)
+	})
+	// Show is expected to be done by caller
+}
+
+// halfBlocks calculates the colors of the upper and lower half of every cell
+// when img is stretched over width by height terminal cells.
+func halfBlocks(img image.Image, width, height int, cell func(x, y int, top, bottom [3]int32)) {
+	bounds := img.Bounds()
+	imgW, imgH := bounds.Dx(), bounds.Dy()
+	pixelsPerConsoleX := float64(imgW) / float64(width)
+	pixelsPerConsoleY := float64(imgH) / float64(height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
 			// Calculate average color for the corresponding image rectangle
 			// fitting in this cell. We use a half-block trick, wherein the
 			// lower half of the cell displays the character ▄, effectively
 			// doubling the resolution of the canvas.
 			startX, startY, endX, endY := imgArea(x, y, pixelsPerConsoleX, pixelsPerConsoleY)
 
-			r, g, b := avgRGB(resizedImg, startX, startY, endX, (startY+endY)/2)
-			r2, g2, b2 := avgRGB(resizedImg, startX, (startY+endY)/2, endX, endY)
-			style := termbox.StyleDefault.
-				Background(termbox.NewRGBColor(r, g, b)).
-				Foreground(termbox.NewRGBColor(r2, g2, b2))
-			screen.SetContent(x, y, '▄', nil, style)
+			r, g, b := avgRGB(img, startX, startY, endX, (startY+endY)/2)
+			r2, g2, b2 := avgRGB(img, startX, (startY+endY)/2, endX, endY)
+			cell(x, y, [3]int32{r, g, b}, [3]int32{r2, g2, b2})
 		}
 	}
-	// Show is expected to be done by caller
+}
+
+// PrintImage writes img to w as width columns of true color half-blocks, for
+// showing a monKey in a terminal without taking over the screen.
+func PrintImage(w io.Writer, img image.Image, width int) error {
+	bounds := img.Bounds()
+	// every cell is twice as high as it is wide and holds two pixels
+	height := (width*bounds.Dy()/bounds.Dx() + 1) / 2
+	var out bytes.Buffer
+	halfBlocks(img, width, height, func(x, y int, top, bottom [3]int32) {
+		fmt.Fprintf(&out, "\x1b[48;2;%d;%d;%dm\x1b[38;2;%d;%d;%dm▄", top[0], top[1], top[2], bottom[0], bottom[1], bottom[2])
+		if x == width-1 {
+			out.WriteString("\x1b[0m\n")
+		}
+	})
+	_, err := w.Write(out.Bytes())
+	return err
 }
 
 func display(ctx context.Context, screen termbox.Screen, img image.Image) error {
@@ -231,7 +255,7 @@ func DisplayImage(ctx context.Context, screen termbox.Screen, imgData io.Reader,
 
 	data, err := io.ReadAll(imgData)
 	if err != nil {
-		log.Errorf("could not read monkey image data to display: %")
+		log.Errorf("could not read monkey image data to display: %s", err)
 		return
 	}
 	imagePNG, err := ConvertSvgToBinary(data, PNGFormat, 250)
@@ -0,0 +1,353 @@
+// Package index keeps found monKeys in an embedded database so they can be
+// searched by trait, rarity and session without reading every saved file.
+// Only addresses, traits and file paths are indexed, never secrets.
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/engine"
+	bolt "go.etcd.io/bbolt"
+)
+
+// FileName is the name of the index in the output directory.
+const FileName = "index.db"
+
+// Ways to sort query results.
+const (
+	SortRarity = "rarity"
+	SortFound  = "found"
+	SortName   = "name"
+)
+
+var (
+	monkeysBucket = []byte("monkeys")
+	traitsBucket  = []byte("traits")
+)
+
+// ErrLocked is returned when another run holds the index for too long.
+var ErrLocked = errors.New("index is in use by another run")
+
+// Entry is a found monKey in the index. Traits are by the category names of
+// engine.MonkeyOdds, OneIn is how many monKeys look just like it.
+type Entry struct {
+	Address   string            `json:"address"`
+	SillyName string            `json:"silly_name"`
+	KeyType   string            `json:"key_type"`
+	Session   string            `json:"session,omitempty"`
+	Found     time.Time         `json:"found"`
+	Traits    map[string]string `json:"traits"`
+	OneIn     float64           `json:"one_in"`
+	Files     []string          `json:"files"`
+}
+
+// NewEntry makes the entry of a monKey saved in files. It keeps the session
+// and found time of monkey, a monKey without them was found now in session.
+func NewEntry(monkey engine.MonkeyStats, session string, files []string) Entry {
+	if monkey.Session != "" {
+		session = monkey.Session
+	}
+	found := monkey.Found.UTC()
+	if monkey.Found.IsZero() {
+		found = time.Now().UTC()
+	}
+	odds, oneIn := engine.MonkeyOdds(monkey)
+	traits := make(map[string]string, len(odds))
+	for _, trait := range odds {
+		traits[trait.Category] = trait.Trait
+	}
+	return Entry{
+		Address:   monkey.PublicAddress,
+		SillyName: monkey.SillyName,
+		KeyType:   string(monkey.KeyType),
+		Session:   session,
+		Found:     found,
+		Traits:    traits,
+		OneIn:     oneIn,
+		Files:     files,
+	}
+}
+
+// Index is an open index, it is locked for other runs until Close.
+type Index struct {
+	db *bolt.DB
+}
+
+// Open opens the index in fileName, creating it unless readOnly. It waits
+// up to timeout for another run to let go of it.
+func Open(fileName string, readOnly bool, timeout time.Duration) (*Index, error) {
+	db, err := bolt.Open(fileName, 0600, &bolt.Options{ReadOnly: readOnly, Timeout: timeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open index: %w", err)
+	}
+	if readOnly {
+		return &Index{db: db}, nil
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(monkeysBucket)
+		if err == nil {
+			_, err = tx.CreateBucketIfNotExists(traitsBucket)
+		}
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create index: %w", err)
+	}
+	return &Index{db: db}, nil
+}
+
+func (index *Index) Close() error {
+	return index.db.Close()
+}
+
+// Add indexes entry. A monKey already in the index keeps its session and
+// found time and gets the files of entry added to its own.
+func (index *Index) Add(entry Entry) error {
+	if entry.Address == "" {
+		return errors.New("cannot index a monKey without an address")
+	}
+	return index.db.Update(func(tx *bolt.Tx) error {
+		monkeys := tx.Bucket(monkeysBucket)
+		traits := tx.Bucket(traitsBucket)
+		if data := monkeys.Get([]byte(entry.Address)); data != nil {
+			var old Entry
+			err := json.Unmarshal(data, &old)
+			if err != nil {
+				return fmt.Errorf("could not read index entry of %s: %w", entry.Address, err)
+			}
+			for category, trait := range old.Traits {
+				if bucket := traits.Bucket([]byte(category)); bucket != nil {
+					bucket.Delete(traitKey(trait, entry.Address))
+				}
+			}
+			if old.Session != "" {
+				entry.Session = old.Session
+			}
+			if !old.Found.IsZero() {
+				entry.Found = old.Found
+			}
+			entry.Files = mergeFiles(old.Files, entry.Files)
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		err = monkeys.Put([]byte(entry.Address), data)
+		if err != nil {
+			return err
+		}
+		for category, trait := range entry.Traits {
+			bucket, err := traits.CreateBucketIfNotExists([]byte(category))
+			if err != nil {
+				return err
+			}
+			err = bucket.Put(traitKey(trait, entry.Address), nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddFile indexes a monKey json file saved by the directory output, with the
+// image next to it. The file time is taken as the time it was found.
+func (index *Index) AddFile(fileName string) error {
+	fileName, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	monkey, err := engine.LoadMonkeyFile(fileName)
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	files := []string{fileName}
+	base := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	for _, extension := range []string{".png", ".svg"} {
+		if _, err := os.Stat(base + extension); err == nil {
+			files = append(files, base+extension)
+		}
+	}
+	entry := NewEntry(monkey, "", files)
+	// files of schema version 1 only have when they were last changed
+	if monkey.Found.IsZero() {
+		entry.Found = stat.ModTime().UTC()
+	}
+	return index.Add(entry)
+}
+
+// Query says which monKeys to look up.
+type Query struct {
+	// Filter picks monKeys the same way a search does, traits by prefix and
+	// any of the address filters.
+	Filter engine.CmdLineFilter
+	// Session when set only matches monKeys found in it.
+	Session string
+	// SortBy is SortRarity, rarest first, SortFound, newest first, or
+	// SortName.
+	SortBy string
+	// Limit when above 0 is the most entries returned.
+	Limit int
+}
+
+// Query returns the monKeys matching query. Only the trait indexes of the
+// filtered categories are read, not every entry.
+func (index *Index) Query(query Query) ([]Entry, error) {
+	pattern, err := query.Filter.AddressPattern()
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	err = index.db.View(func(tx *bolt.Tx) error {
+		monkeys := tx.Bucket(monkeysBucket)
+		if monkeys == nil {
+			return nil
+		}
+		add := func(data []byte) error {
+			var entry Entry
+			err := json.Unmarshal(data, &entry)
+			if err != nil {
+				return fmt.Errorf("could not read index entry: %w", err)
+			}
+			if query.Session != "" && entry.Session != query.Session {
+				return nil
+			}
+			if !pattern.Match(entry.Address) {
+				return nil
+			}
+			entries = append(entries, entry)
+			return nil
+		}
+
+		addresses, filtered := matchTraits(tx.Bucket(traitsBucket), query.Filter)
+		if !filtered {
+			return monkeys.ForEach(func(address, data []byte) error {
+				return add(data)
+			})
+		}
+		for address := range addresses {
+			if data := monkeys.Get([]byte(address)); data != nil {
+				err := add(data)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = sortEntries(entries, query.SortBy)
+	if err != nil {
+		return nil, err
+	}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+	}
+	return entries, nil
+}
+
+// matchTraits finds the addresses with any of the traits of every filtered
+// category, filtered is false when the filter has no traits at all.
+func matchTraits(traits *bolt.Bucket, filter engine.CmdLineFilter) (addresses map[string]bool, filtered bool) {
+	categories := []struct {
+		name     string
+		prefixes []string
+	}{
+		{"glasses", filter.Glasses},
+		{"hat", filter.Hat},
+		{"misc", filter.Misc},
+		{"mouth", filter.Mouth},
+		{"cloths", filter.Cloths},
+		{"feet", filter.Feet},
+		{"tail", filter.Tail},
+	}
+	for _, category := range categories {
+		if len(category.prefixes) == 0 {
+			continue
+		}
+		matches := make(map[string]bool)
+		var bucket *bolt.Bucket
+		if traits != nil {
+			bucket = traits.Bucket([]byte(category.name))
+		}
+		if bucket != nil {
+			cursor := bucket.Cursor()
+			for _, prefix := range category.prefixes {
+				for key, _ := cursor.Seek([]byte(prefix)); key != nil && bytes.HasPrefix(key, []byte(prefix)); key, _ = cursor.Next() {
+					address := key[bytes.IndexByte(key, 0)+1:]
+					if !filtered || addresses[string(address)] {
+						matches[string(address)] = true
+					}
+				}
+			}
+		}
+		addresses, filtered = matches, true
+	}
+	return addresses, filtered
+}
+
+func sortEntries(entries []Entry, sortBy string) error {
+	var less func(a, b Entry) bool
+	switch sortBy {
+	case SortRarity, "":
+		less = func(a, b Entry) bool { return a.OneIn > b.OneIn }
+	case SortFound:
+		less = func(a, b Entry) bool { return a.Found.After(b.Found) }
+	case SortName:
+		less = func(a, b Entry) bool { return a.SillyName < b.SillyName }
+	default:
+		return fmt.Errorf("cannot sort by %s", sortBy)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if less(entries[i], entries[j]) {
+			return true
+		}
+		if less(entries[j], entries[i]) {
+			return false
+		}
+		return entries[i].Address < entries[j].Address
+	})
+	return nil
+}
+
+// traitKey sorts the addresses of a category by trait, so a trait prefix is
+// a range of keys.
+func traitKey(trait string, address string) []byte {
+	return []byte(trait + "\x00" + address)
+}
+
+func mergeFiles(old []string, files []string) []string {
+	merged := append([]string(nil), old...)
+	for _, file := range files {
+		known := false
+		for _, have := range merged {
+			if have == file {
+				known = true
+				break
+			}
+		}
+		if !known {
+			merged = append(merged, file)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,226 @@
+package index_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/bananoutils"
+	"github.com/steampoweredtaco/legion-van/engine"
+	"github.com/steampoweredtaco/legion-van/index"
+)
+
+const (
+	crownCigarAddress = "ban_3wtsduys8b7jkbfwwfzx3jgpgpsi9b8zurfe9bp1p5cdxkqiz7a5wxcoo7ba"
+	crownPipeAddress  = "ban_1k63i7emu4zhsdgi3uaxptpamc5xkwrk68x37bbxghnkybwh9wz4qajrkyst"
+	capCigarAddress   = "ban_3i1aq1cchnmbn9x5rsbap8b15akfh7wj7pwskuzi7ahz8oq6cobd99d4r3b7"
+)
+
+func testMonkey(name string, address string, hat string, mouth string) engine.MonkeyStats {
+	var monkey engine.MonkeyStats
+	monkey.SillyName = name
+	monkey.PublicAddress = address
+	monkey.PrivateKey = bananoutils.Secret(strings.Repeat("ab", 32))
+	monkey.KeyType = bananoutils.KeyTypeSeed
+	monkey.Hat = hat
+	monkey.Mouth = mouth
+	monkey.Glasses, monkey.Misc, monkey.ShirtPants, monkey.Shoes, monkey.Tail = "none", "none", "none", "none", "none"
+	return monkey
+}
+
+func names(entries []index.Entry) string {
+	var found []string
+	for _, entry := range entries {
+		found = append(found, entry.SillyName)
+	}
+	return strings.Join(found, " ")
+}
+
+func TestQuery(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), index.FileName)
+	sink := index.NewSink(fileName, func(session string, found engine.FoundMonkey) []string {
+		return []string{"/found/" + session + "/" + found.FileName() + ".json"}
+	})
+	err := sink.Open("session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, monkey := range []engine.MonkeyStats{
+		testMonkey("Royal", crownCigarAddress, "crown-[unique][w-0.225].svg", "cigar-[w-0.5].svg"),
+		testMonkey("Piper", crownPipeAddress, "crown-[unique][w-0.225].svg", "pipe-[w-0.5].svg"),
+		testMonkey("Capper", capCigarAddress, "cap-banano-[w-0.8].svg", "cigar-[w-0.5].svg"),
+	} {
+		err = sink.Write(engine.FoundMonkey{Monkey: monkey, ImageExtension: ".png"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Close()
+
+	found, err := index.Open(fileName, true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer found.Close()
+	tests := []struct {
+		name     string
+		query    index.Query
+		expected string
+	}{
+		{"everything rarest first", index.Query{}, "Piper Royal Capper"},
+		{"crown and cigar", index.Query{Filter: engine.CmdLineFilter{Hat: []string{"crown"}, Mouth: []string{"cigar"}}}, "Royal"},
+		{"any cigar by name", index.Query{Filter: engine.CmdLineFilter{Mouth: []string{"cigar"}}, SortBy: index.SortName}, "Capper Royal"},
+		{"crown or cap and a pipe", index.Query{Filter: engine.CmdLineFilter{Hat: []string{"crown", "cap"}, Mouth: []string{"pipe"}}}, "Piper"},
+		{"unknown trait", index.Query{Filter: engine.CmdLineFilter{Glasses: []string{"monocle"}}}, ""},
+		{"address prefix", index.Query{Filter: engine.CmdLineFilter{AddressPrefix: "1k6"}}, "Piper"},
+		{"other session", index.Query{Session: "session-2"}, ""},
+		{"limit", index.Query{Limit: 1}, "Piper"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := found.Query(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(entries); got != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+
+	entries, err := found.Query(index.Query{Filter: engine.CmdLineFilter{Hat: []string{"crown"}, Mouth: []string{"cigar"}}})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected Royal, got %v %v", entries, err)
+	}
+	royal := entries[0]
+	if royal.Session != "session-1" || royal.OneIn < 4096 || royal.Traits["hat"] != "crown-[unique][w-0.225].svg" {
+		t.Errorf("unexpected entry %+v", royal)
+	}
+	if len(royal.Files) != 1 || royal.Files[0] != "/found/session-1/Royal_"+crownCigarAddress+".json" {
+		t.Errorf("expected the file of the monKey, got %v", royal.Files)
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), strings.Repeat("ab", 32)) {
+		t.Error("the index must not hold secrets")
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	dir := t.TempDir()
+	monkeyFile := filepath.Join(dir, "Royal_"+crownCigarAddress+".json")
+	err := ioutil.WriteFile(monkeyFile, []byte(`{"public_address":"`+crownCigarAddress+`","hat":"crown-[unique][w-0.225].svg","mouth":"pipe-[w-0.5].svg"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "Royal_"+crownCigarAddress+".svg"), []byte("<svg/>"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := index.Open(filepath.Join(dir, index.FileName), false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer found.Close()
+	// indexing again replaces the traits and keeps the files
+	err = found.Add(index.NewEntry(testMonkey("Royal", crownCigarAddress, "crown-[unique][w-0.225].svg", "cigar-[w-0.5].svg"), "session-1", []string{"archive.tar"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = found.AddFile(monkeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := found.Query(index.Query{Filter: engine.CmdLineFilter{Mouth: []string{"cigar"}}})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expected the old trait to be gone, got %v %v", entries, err)
+	}
+	entries, err = found.Query(index.Query{Filter: engine.CmdLineFilter{Mouth: []string{"pipe"}}})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected the monKey by its new trait, got %v %v", entries, err)
+	}
+	entry := entries[0]
+	expected := "archive.tar " + monkeyFile + " " + strings.TrimSuffix(monkeyFile, ".json") + ".svg"
+	if entry.SillyName != "Royal" || entry.Session != "session-1" || strings.Join(entry.Files, " ") != expected {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestSinkKeepsEarlierSession(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), index.FileName)
+	sink := index.NewSink(fileName, func(session string, found engine.FoundMonkey) []string {
+		return []string{found.FileName() + ".json"}
+	})
+	err := sink.Open("session-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// finished from the pending queue of an earlier run
+	monkey := testMonkey("Royal", crownCigarAddress, "crown-[unique][w-0.225].svg", "cigar-[w-0.5].svg")
+	monkey.Session = "session-1"
+	monkey.Found = time.Date(2021, 5, 4, 3, 2, 1, 0, time.UTC)
+	err = sink.Write(engine.FoundMonkey{Monkey: monkey, ImageExtension: ".png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	found, err := index.Open(fileName, true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer found.Close()
+	entries, err := found.Query(index.Query{Session: "session-1"})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected Royal in the session that found it, got %v %v", entries, err)
+	}
+	if !entries[0].Found.Equal(monkey.Found) {
+		t.Errorf("expected it found at %s, got %s", monkey.Found, entries[0].Found)
+	}
+}
+
+func TestSinkBurst(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), index.FileName)
+	sink := index.NewSink(fileName, func(session string, found engine.FoundMonkey) []string {
+		return []string{found.FileName() + ".json"}
+	})
+	err := sink.Open("session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every output goroutine finds at once
+	addresses := []string{crownCigarAddress, crownPipeAddress, capCigarAddress}
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			monkey := testMonkey("Burst", addresses[i%len(addresses)], "crown-[unique][w-0.225].svg", "cigar-[w-0.5].svg")
+			errs <- sink.Write(engine.FoundMonkey{Monkey: monkey, ImageExtension: ".png"})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	sink.Close()
+
+	found, err := index.Open(fileName, true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer found.Close()
+	entries, err := found.Query(index.Query{})
+	if err != nil || len(entries) != len(addresses) {
+		t.Errorf("expected all %d monKeys indexed, got %v %v", len(addresses), entries, err)
+	}
+}
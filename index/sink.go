@@ -0,0 +1,62 @@
+package index
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/steampoweredtaco/legion-van/engine"
+)
+
+// writeTimeout is how long a found monKey waits for a query to let go of the
+// index.
+const writeTimeout = 10 * time.Second
+
+type sink struct {
+	fileName string
+	files    func(session string, found engine.FoundMonkey) []string
+	session  string
+
+	// mu lets one Write at a time open the index, the file lock of the index
+	// is no queue and would time out under a burst of finds
+	mu sync.Mutex
+}
+
+// NewSink indexes every found monKey in the index in fileName, files says
+// where the other sinks saved it. The index is only opened while a monKey is
+// added so queries can run during a search.
+func NewSink(fileName string, files func(session string, found engine.FoundMonkey) []string) engine.Sink {
+	return &sink{fileName: fileName, files: files}
+}
+
+func (sink *sink) Open(session string) error {
+	sink.session = session
+	// fail before the search starts when the index is broken
+	index, err := Open(sink.fileName, false, writeTimeout)
+	if err != nil {
+		return err
+	}
+	return index.Close()
+}
+
+func (sink *sink) Write(found engine.FoundMonkey) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	index, err := Open(sink.fileName, false, writeTimeout)
+	if err != nil {
+		return err
+	}
+	err = index.Add(NewEntry(found.Monkey, sink.session, sink.files(sink.session, found)))
+	closeErr := index.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not index monKey %s: %w", found.Monkey.SillyName, err)
+	}
+	return nil
+}
+
+func (sink *sink) Close() error {
+	return nil
+}